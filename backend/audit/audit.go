@@ -0,0 +1,56 @@
+// Package audit stores an append-only trail of security-relevant events.
+package audit
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Actions recorded in the trail.
+const (
+	ActionAccountLocked   = "account.locked"
+	ActionAccountUnlocked = "account.unlocked"
+	ActionIPLocked        = "ip.locked"
+
+	ActionUserRoleChanged     = "user.role_changed"
+	ActionUserDeactivated     = "user.deactivated"
+	ActionUserReactivated     = "user.reactivated"
+	ActionPasswordResetForced = "user.password_reset_forced"
+)
+
+type Log struct {
+	col *mongo.Collection
+}
+
+func New(db *mongo.Database) *Log {
+	return &Log{col: db.Collection("audit_log")}
+}
+
+// Record appends entry. Failures are logged rather than returned: losing an
+// audit line must not fail the request that caused it.
+func (l *Log) Record(ctx context.Context, entry models.AuditEntry) {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	if _, err := l.col.InsertOne(ctx, entry); err != nil {
+		log.Printf("audit %s: %v", entry.Action, err)
+	}
+}
+
+func (l *Log) EnsureIndexes(ctx context.Context) error {
+	_, err := l.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "orgId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "targetId", Value: 1}}},
+	})
+	return err
+}
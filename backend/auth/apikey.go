@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APIKeyPrefix starts every API key so it can be told apart from a JWT.
+const APIKeyPrefix = "wbk_"
+
+// APIKeyHeader is an alternative to "Authorization: Bearer wbk_...".
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyResources are the /api/<resource> path segments a key can be scoped
+// to, as "<resource>:read" (GET) or "<resource>:write" (everything else).
+// Auth, session, 2FA and key management routes are deliberately absent so a
+// leaked key cannot be used to escalate.
+var APIKeyResources = []string{"checkins", "corrections", "moods", "mood-scales", "timesheet", "leave", "holidays", "users", "user", "teams", "projects", "org"}
+
+// apiKeyReadOnly are resources keys may only read. Writes there change
+// roles, lock users out or purge data, which a leaked key must not do.
+var apiKeyReadOnly = map[string]bool{"users": true, "org": true}
+
+// apiKeyDeniedPaths are credential routes inside scoped resources that keys
+// may never call. Email changes through /api/user/profile are refused in
+// the handler, since the rest of the profile is fair game.
+var apiKeyDeniedPaths = []string{"/api/user/password", "/api/user/2fa"}
+
+// ErrInvalidScope is returned for scopes outside APIKeyResources.
+var ErrInvalidScope = errors.New("invalid API key scope")
+
+// ValidateScopes normalizes scopes and rejects unknown ones.
+func ValidateScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+	seen := map[string]bool{}
+	out := []string{}
+	for _, s := range scopes {
+		s = strings.ToLower(strings.TrimSpace(s))
+		resource, access, ok := strings.Cut(s, ":")
+		if !ok || (access != "read" && access != "write") || !knownResource(resource) || (access == "write" && apiKeyReadOnly[resource]) {
+			return nil, ErrInvalidScope
+		}
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func knownResource(resource string) bool {
+	for _, r := range APIKeyResources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePath lower-cases path and collapses repeated and trailing
+// slashes, matching how Fiber routes it.
+func normalizePath(path string) string {
+	path = strings.ToLower(path)
+	for strings.Contains(path, "//") {
+		path = strings.ReplaceAll(path, "//", "/")
+	}
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+// scopeAllows reports whether scopes cover method on path.
+func scopeAllows(scopes []string, method, path string) bool {
+	path = normalizePath(path)
+	for _, denied := range apiKeyDeniedPaths {
+		if path == denied || strings.HasPrefix(path, denied+"/") {
+			return false
+		}
+	}
+	rest, ok := strings.CutPrefix(path, "/api/")
+	if !ok {
+		return false
+	}
+	resource, _, _ := strings.Cut(rest, "/")
+	access := "write"
+	if method == fiber.MethodGet || method == fiber.MethodHead {
+		access = "read"
+	} else if apiKeyReadOnly[resource] {
+		return false
+	}
+	want := resource + ":" + access
+	for _, s := range scopes {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPIKey issues a key acting as owner. The returned secret is shown
+// once; only its hash is kept.
+func (m *Manager) CreateAPIKey(ctx context.Context, owner models.User, name string, scopes []string, expiresAt *time.Time, createdBy primitive.ObjectID) (string, *models.APIKey, error) {
+	secret, _, err := NewSecret()
+	if err != nil {
+		return "", nil, err
+	}
+	key := APIKeyPrefix + secret
+	apiKey := &models.APIKey{
+		ID:        primitive.NewObjectID(),
+		OrgID:     owner.OrgID,
+		UserID:    owner.ID,
+		Name:      name,
+		Prefix:    key[:len(APIKeyPrefix)+6],
+		KeyHash:   HashSecret(key),
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	if _, err := m.apiKeys.InsertOne(ctx, apiKey); err != nil {
+		return "", nil, err
+	}
+	return key, apiKey, nil
+}
+
+// ListAPIKeys returns userID's keys that have not been revoked.
+func (m *Manager) ListAPIKeys(ctx context.Context, userID primitive.ObjectID) ([]models.APIKey, error) {
+	cur, err := m.apiKeys.Find(ctx,
+		bson.M{"userId": userID, "revokedAt": bson.M{"$exists": false}},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	keys := []models.APIKey{}
+	if err := cur.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes one of userID's keys.
+func (m *Manager) RevokeAPIKey(ctx context.Context, userID, keyID primitive.ObjectID) (bool, error) {
+	res, err := m.apiKeys.UpdateOne(ctx,
+		bson.M{"_id": keyID, "userId": userID, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// authenticateAPIKey resolves key to the owner's Principal, or nil when the
+// key is unknown, revoked or expired.
+func (m *Manager) authenticateAPIKey(ctx context.Context, key string) (*Principal, error) {
+	now := time.Now()
+	var apiKey models.APIKey
+	err := m.apiKeys.FindOne(ctx, bson.M{
+		"keyHash":   HashSecret(key),
+		"revokedAt": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"expiresAt": bson.M{"$exists": false}},
+			bson.M{"expiresAt": bson.M{"$gt": now}},
+		},
+	}).Decode(&apiKey)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var user models.User
+	err = m.users.FindOne(ctx, bson.M{"_id": apiKey.UserID, "orgId": apiKey.OrgID, "deactivated": bson.M{"$ne": true}}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.LastUsedAt == nil || apiKey.LastUsedAt.Before(now.Add(-lastSeenInterval)) {
+		_, _ = m.apiKeys.UpdateOne(ctx, bson.M{"_id": apiKey.ID}, bson.M{"$set": bson.M{"lastUsedAt": now}})
+	}
+	return &Principal{
+		UserID:   user.ID,
+		OrgID:    user.OrgID,
+		Name:     user.Name,
+		Email:    user.Email,
+		Role:     user.Role,
+		APIKeyID: apiKey.ID,
+		Scopes:   apiKey.Scopes,
+	}, nil
+}
+
+// apiKeyAuth handles a request carrying an API key instead of a JWT.
+func (m *Manager) apiKeyAuth(c *fiber.Ctx, key string) error {
+	p, err := m.authenticateAPIKey(context.Background(), key)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if p == nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid API key"})
+	}
+	if !scopeAllows(p.Scopes, c.Method(), c.Path()) {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "API key scope does not allow this request", "code": "insufficient_scope"})
+	}
+	c.Locals(principalKey, p)
+	return c.Next()
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestScopeAllows(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		method string
+		path   string
+		want   bool
+	}{
+		{"read scope allows GET", []string{"checkins:read"}, fiber.MethodGet, "/api/checkins", true},
+		{"read scope allows HEAD", []string{"checkins:read"}, fiber.MethodHead, "/api/checkins/1", true},
+		{"read scope denies POST", []string{"checkins:read"}, fiber.MethodPost, "/api/checkins", false},
+		{"write scope allows POST", []string{"checkins:write"}, fiber.MethodPost, "/api/checkins", true},
+		{"write scope denies GET", []string{"checkins:write"}, fiber.MethodGet, "/api/checkins", false},
+		{"other resource", []string{"teams:read"}, fiber.MethodGet, "/api/checkins", false},
+		{"resource prefix is not a match", []string{"user:read"}, fiber.MethodGet, "/api/users", false},
+		{"outside the api", []string{"checkins:read"}, fiber.MethodGet, "/files/x", false},
+		{"mixed case path", []string{"checkins:read"}, fiber.MethodGet, "/API/Checkins", true},
+		{"denied password route", []string{"user:write"}, fiber.MethodPut, "/api/user/password", false},
+		{"denied route in other case", []string{"user:write"}, fiber.MethodPut, "/api/user/Password", false},
+		{"denied route with trailing slash", []string{"user:write"}, fiber.MethodPut, "/api/user/password/", false},
+		{"denied route with doubled slashes", []string{"user:write"}, fiber.MethodPut, "/api//user//password", false},
+		{"denied 2fa subtree", []string{"user:write"}, fiber.MethodPost, "/api/user/2FA/disable", false},
+		{"profile stays reachable", []string{"user:write"}, fiber.MethodPut, "/api/user/profile", true},
+		{"no scopes", nil, fiber.MethodGet, "/api/checkins", false},
+		{"users can be listed", []string{"users:read"}, fiber.MethodGet, "/api/users", true},
+		{"role change refused", []string{"users:write"}, fiber.MethodPut, "/api/users/x/role", false},
+		{"deactivation refused", []string{"users:write"}, fiber.MethodPost, "/api/users/x/deactivate", false},
+		{"org can be read", []string{"org:read"}, fiber.MethodGet, "/api/org", true},
+		{"org settings refused", []string{"org:write"}, fiber.MethodPut, "/api/org", false},
+		{"selfie purge refused", []string{"org:write"}, fiber.MethodPost, "/API/Org/selfie-purges", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scopeAllows(tt.scopes, tt.method, tt.path); got != tt.want {
+				t.Errorf("scopeAllows(%v, %s, %q) = %v, want %v", tt.scopes, tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	tests := []struct {
+		scopes  []string
+		want    []string
+		wantErr bool
+	}{
+		{[]string{" Checkins:READ ", "checkins:read", "teams:write"}, []string{"checkins:read", "teams:write"}, false},
+		{nil, nil, true},
+		{[]string{"checkins"}, nil, true},
+		{[]string{"checkins:delete"}, nil, true},
+		{[]string{"apikeys:read"}, nil, true},
+		{[]string{"users:write"}, nil, true},
+		{[]string{"org:write"}, nil, true},
+		{[]string{"users:read", "org:read"}, []string{"users:read", "org:read"}, false},
+	}
+	for _, tt := range tests {
+		got, err := ValidateScopes(tt.scopes)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateScopes(%v) error = %v, want error %v", tt.scopes, err, tt.wantErr)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("ValidateScopes(%v) = %v, want %v", tt.scopes, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("ValidateScopes(%v) = %v, want %v", tt.scopes, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,297 @@
+// Package auth issues and verifies the access tokens used by every route
+// group and exposes the authenticated caller to handlers as a Principal.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"backend/audit"
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultIssuer     = "wellbeing-check"
+	defaultAudience   = "wellbeing-check-api"
+	defaultTokenTTL   = 15 * time.Minute
+	defaultRefreshTTL = 30 * 24 * time.Hour
+	challengeTTL      = 5 * time.Minute
+
+	principalKey = "auth.principal"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID    primitive.ObjectID
+	OrgID     primitive.ObjectID
+	SessionID primitive.ObjectID
+	Name      string
+	Email     string
+	Role      string
+	// EnrollOnly is set while the user must enroll in two-factor
+	// authentication before using the rest of the API.
+	EnrollOnly bool
+	// APIKeyID and Scopes are set when the request used an API key rather
+	// than a session; SessionID is then zero.
+	APIKeyID primitive.ObjectID
+	Scopes   []string
+}
+
+// Claims is the payload of an access token. The user ID travels in the
+// standard "sub" claim, the organization in "org" and the session ID in
+// "sid".
+type Claims struct {
+	OrgID     string `json:"org"`
+	SessionID string `json:"sid"`
+	// EnrollOnly marks sessions that may only enroll in two-factor
+	// authentication because the user's role requires it.
+	EnrollOnly bool   `json:"enroll,omitempty"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+	Role       string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// Manager signs and validates access tokens and owns the sessions behind
+// them.
+type Manager struct {
+	secret     []byte
+	issuer     string
+	audience   string
+	ttl        time.Duration
+	refreshTTL time.Duration
+	parser     *jwt.Parser
+	challenges *jwt.Parser
+	users      *mongo.Collection
+	sessions   *mongo.Collection
+	userTokens *mongo.Collection
+	attempts   *mongo.Collection
+	apiKeys    *mongo.Collection
+	lockout    LockoutPolicy
+	audit      *audit.Log
+}
+
+// NewManager builds a Manager from JWT_SECRET, JWT_ISSUER, JWT_AUDIENCE,
+// JWT_TTL, REFRESH_TTL and the LOGIN_* lockout settings. JWT_SECRET is
+// required.
+func NewManager(db *mongo.Database) (*Manager, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, errors.New("JWT_SECRET is not set")
+	}
+	issuer := envOr("JWT_ISSUER", defaultIssuer)
+	audience := envOr("JWT_AUDIENCE", defaultAudience)
+	ttl, err := envDuration("JWT_TTL", defaultTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	refreshTTL, err := envDuration("REFRESH_TTL", defaultRefreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	lockout, err := lockoutPolicyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{
+		secret:     []byte(secret),
+		issuer:     issuer,
+		audience:   audience,
+		ttl:        ttl,
+		refreshTTL: refreshTTL,
+		users:      db.Collection("users"),
+		sessions:   db.Collection("sessions"),
+		userTokens: db.Collection("user_tokens"),
+		attempts:   db.Collection("login_attempts"),
+		apiKeys:    db.Collection("api_keys"),
+		lockout:    lockout,
+		audit:      audit.New(db),
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+			jwt.WithIssuer(issuer),
+			jwt.WithAudience(audience),
+			jwt.WithExpirationRequired(),
+			jwt.WithIssuedAt(),
+		),
+		challenges: jwt.NewParser(
+			jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+			jwt.WithIssuer(issuer),
+			jwt.WithAudience(challengeAudience(audience)),
+			jwt.WithExpirationRequired(),
+		),
+	}, nil
+}
+
+// IssueToken returns a signed access token for user bound to sessionID.
+func (m *Manager) IssueToken(user models.User, sessionID primitive.ObjectID, enrollOnly bool) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		OrgID:      user.OrgID.Hex(),
+		SessionID:  sessionID.Hex(),
+		EnrollOnly: enrollOnly,
+		Name:       user.Name,
+		Email:      user.Email,
+		Role:       user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.ID.Hex(),
+			Issuer:    m.issuer,
+			Audience:  jwt.ClaimStrings{m.audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.ttl)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+}
+
+// ParseToken validates tokenStr and returns its claims.
+func (m *Manager) ParseToken(tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := m.parser.ParseWithClaims(tokenStr, claims, func(*jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// IssueChallenge returns a short-lived token proving that user passed the
+// password step of a two-factor login. It is not accepted as an access
+// token.
+func (m *Manager) IssueChallenge(user models.User) (string, error) {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		Subject:   user.ID.Hex(),
+		Issuer:    m.issuer,
+		Audience:  jwt.ClaimStrings{challengeAudience(m.audience)},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(challengeTTL)),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+}
+
+// ParseChallenge validates a token from IssueChallenge and returns the user
+// it was issued to.
+func (m *Manager) ParseChallenge(tokenStr string) (primitive.ObjectID, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := m.challenges.ParseWithClaims(tokenStr, claims, func(*jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return primitive.ObjectIDFromHex(claims.Subject)
+}
+
+func challengeAudience(audience string) string {
+	return audience + "/mfa"
+}
+
+// Required rejects requests without a valid Bearer token or API key, or
+// whose session has been revoked, and stores the caller's Principal for
+// PrincipalFrom.
+// Enrollment-only sessions are refused; see RequiredAllowEnrollment.
+func (m *Manager) Required() fiber.Handler {
+	return m.required(false)
+}
+
+// RequiredAllowEnrollment is Required for the few routes a user who still
+// has to set up two-factor authentication may call.
+func (m *Manager) RequiredAllowEnrollment() fiber.Handler {
+	return m.required(true)
+}
+
+func (m *Manager) required(allowEnrollOnly bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if key := c.Get(APIKeyHeader); key != "" {
+			return m.apiKeyAuth(c, key)
+		}
+		tokenStr, ok := bearerToken(c.Get(fiber.HeaderAuthorization))
+		if !ok {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Missing or invalid token"})
+		}
+		if strings.HasPrefix(tokenStr, APIKeyPrefix) {
+			return m.apiKeyAuth(c, tokenStr)
+		}
+		claims, err := m.ParseToken(tokenStr)
+		if err != nil {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+		}
+		userID, err := primitive.ObjectIDFromHex(claims.Subject)
+		if err != nil {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+		}
+		if claims.EnrollOnly && !allowEnrollOnly {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Two-factor authentication must be set up first", "code": "mfa_enrollment_required"})
+		}
+		orgID, err := primitive.ObjectIDFromHex(claims.OrgID)
+		if err != nil {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+		}
+		sessionID, err := primitive.ObjectIDFromHex(claims.SessionID)
+		if err != nil {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+		}
+		active, err := m.touchSession(context.Background(), sessionID, userID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !active {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Session has been revoked"})
+		}
+		c.Locals(principalKey, &Principal{
+			UserID:     userID,
+			OrgID:      orgID,
+			SessionID:  sessionID,
+			Name:       claims.Name,
+			Email:      claims.Email,
+			Role:       claims.Role,
+			EnrollOnly: claims.EnrollOnly,
+		})
+		return c.Next()
+	}
+}
+
+// PrincipalFrom returns the caller stored by Required, or nil when the route
+// is not behind Required.
+func PrincipalFrom(c *fiber.Ctx) *Principal {
+	p, _ := c.Locals(principalKey).(*Principal)
+	return p
+}
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func envDuration(key string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, errors.New(key + ": " + err.Error())
+	}
+	return d, nil
+}
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/audit"
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LockoutPolicy controls login throttling. Counters live in MongoDB so every
+// replica sees the same state.
+type LockoutPolicy struct {
+	// FreeFailures is how many failures are allowed before delays start.
+	FreeFailures int
+	// MaxDelay caps the doubling delay between attempts.
+	MaxDelay time.Duration
+	// AccountLimit and IPLimit are the failure counts that trigger a lockout.
+	AccountLimit int
+	IPLimit      int
+	Lockout      time.Duration
+	// Window is how long failures are remembered without new ones.
+	Window time.Duration
+}
+
+func lockoutPolicyFromEnv() (LockoutPolicy, error) {
+	p := LockoutPolicy{
+		FreeFailures: 3,
+		MaxDelay:     time.Minute,
+		AccountLimit: 5,
+		IPLimit:      20,
+		Lockout:      15 * time.Minute,
+		Window:       15 * time.Minute,
+	}
+	var err error
+	if p.AccountLimit, err = envInt("LOGIN_MAX_FAILURES", p.AccountLimit); err != nil {
+		return p, err
+	}
+	if p.IPLimit, err = envInt("LOGIN_MAX_FAILURES_IP", p.IPLimit); err != nil {
+		return p, err
+	}
+	if p.Lockout, err = envDuration("LOGIN_LOCKOUT", p.Lockout); err != nil {
+		return p, err
+	}
+	if p.Window, err = envDuration("LOGIN_FAILURE_WINDOW", p.Window); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// ErrLoginThrottled means the caller must wait before trying again.
+type ErrLoginThrottled struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *ErrLoginThrottled) Error() string {
+	if e.Locked {
+		return "too many failed attempts, temporarily locked"
+	}
+	return "too many failed attempts, slow down"
+}
+
+func emailKey(email string) string { return "email:" + strings.ToLower(strings.TrimSpace(email)) }
+func ipKey(ip string) string       { return "ip:" + ip }
+
+// CheckLogin returns an *ErrLoginThrottled when the account or the client IP
+// may not attempt a login right now.
+func (m *Manager) CheckLogin(ctx context.Context, email, ip string) error {
+	cur, err := m.attempts.Find(ctx, bson.M{"_id": bson.M{"$in": bson.A{emailKey(email), ipKey(ip)}}})
+	if err != nil {
+		return err
+	}
+	var docs []models.LoginAttempts
+	if err := cur.All(ctx, &docs); err != nil {
+		return err
+	}
+	now := time.Now()
+	var wait time.Duration
+	locked := false
+	for _, d := range docs {
+		if d.LockedUntil != nil && d.LockedUntil.After(now) {
+			locked = true
+			wait = max(wait, d.LockedUntil.Sub(now))
+		} else if d.NextAttemptAt != nil && d.NextAttemptAt.After(now) {
+			wait = max(wait, d.NextAttemptAt.Sub(now))
+		}
+	}
+	if wait > 0 {
+		return &ErrLoginThrottled{RetryAfter: wait, Locked: locked}
+	}
+	return nil
+}
+
+// LoginFailed counts a failed password or second-factor attempt against
+// both the account and the IP, applying delays and lockouts.
+func (m *Manager) LoginFailed(ctx context.Context, email, ip string) error {
+	if err := m.recordFailure(ctx, emailKey(email), m.lockout.AccountLimit, email, ip); err != nil {
+		return err
+	}
+	return m.recordFailure(ctx, ipKey(ip), m.lockout.IPLimit, email, ip)
+}
+
+// LoginSucceeded clears the account's failure count. The IP count is kept so
+// a valid account cannot be used to reset guessing against others.
+func (m *Manager) LoginSucceeded(ctx context.Context, email string) error {
+	_, err := m.attempts.DeleteOne(ctx, bson.M{"_id": emailKey(email)})
+	return err
+}
+
+// UnlockAccount clears failures and any lockout on user's account and
+// records who lifted it.
+func (m *Manager) UnlockAccount(ctx context.Context, user models.User, actor *Principal, ip string) (bool, error) {
+	res, err := m.attempts.DeleteOne(ctx, bson.M{"_id": emailKey(user.Email)})
+	if err != nil {
+		return false, err
+	}
+	m.audit.Record(ctx, models.AuditEntry{
+		OrgID:    user.OrgID,
+		Action:   audit.ActionAccountUnlocked,
+		ActorID:  &actor.UserID,
+		TargetID: &user.ID,
+		IP:       ip,
+		Details:  map[string]any{"email": user.Email, "wasLocked": res.DeletedCount > 0},
+	})
+	return res.DeletedCount > 0, nil
+}
+
+func (m *Manager) recordFailure(ctx context.Context, key string, limit int, email, ip string) error {
+	now := time.Now()
+	p := m.lockout
+	// Reset the count when the previous failure fell outside the window;
+	// done as one pipeline update so concurrent replicas cannot lose counts.
+	var doc models.LoginAttempts
+	err := m.attempts.FindOneAndUpdate(ctx,
+		bson.M{"_id": key},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"failures": bson.M{"$cond": bson.A{
+				bson.M{"$lt": bson.A{"$lastFailureAt", now.Add(-p.Window)}},
+				1,
+				bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$failures", 0}}, 1}},
+			}},
+			"lastFailureAt": now,
+			"expiresAt":     now.Add(p.Window + p.Lockout),
+		}}}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&doc)
+	if err != nil {
+		return err
+	}
+	set := bson.M{}
+	delay, locked := p.penalty(doc.Failures, limit)
+	if locked {
+		lockedUntil := now.Add(p.Lockout)
+		set["lockedUntil"] = lockedUntil
+		if doc.LockedUntil == nil || !doc.LockedUntil.After(now) {
+			m.auditLockout(ctx, key, email, ip, lockedUntil)
+		}
+	} else if delay > 0 {
+		set["nextAttemptAt"] = now.Add(delay)
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	_, err = m.attempts.UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$set": set})
+	return err
+}
+
+// penalty returns what follows the given number of failures against a
+// limit: a lockout once the limit is reached, otherwise a delay that starts
+// at one second after the free failures and doubles up to MaxDelay.
+func (p LockoutPolicy) penalty(failures, limit int) (time.Duration, bool) {
+	if failures >= limit {
+		return 0, true
+	}
+	if failures <= p.FreeFailures {
+		return 0, false
+	}
+	delay := time.Second << (failures - p.FreeFailures - 1)
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+	return delay, false
+}
+
+func (m *Manager) auditLockout(ctx context.Context, key, email, ip string, lockedUntil time.Time) {
+	entry := models.AuditEntry{
+		IP:      ip,
+		Details: map[string]any{"lockedUntil": lockedUntil},
+	}
+	if strings.HasPrefix(key, "ip:") {
+		entry.Action = audit.ActionIPLocked
+	} else {
+		entry.Action = audit.ActionAccountLocked
+		entry.Details["email"] = email
+		var user models.User
+		err := m.users.FindOne(ctx, bson.M{"email": strings.TrimSpace(email)}).Decode(&user)
+		if err == nil {
+			entry.OrgID = user.OrgID
+			entry.TargetID = &user.ID
+		} else if !errors.Is(err, mongo.ErrNoDocuments) {
+			entry.Details["lookupError"] = err.Error()
+		}
+	}
+	m.audit.Record(ctx, entry)
+}
+
+func envInt(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, errors.New(key + ": must be a positive integer")
+	}
+	return n, nil
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutPenalty(t *testing.T) {
+	p := LockoutPolicy{FreeFailures: 3, MaxDelay: time.Minute}
+	tests := []struct {
+		failures int
+		limit    int
+		delay    time.Duration
+		locked   bool
+	}{
+		{1, 5, 0, false},
+		{3, 5, 0, false},
+		{4, 5, time.Second, false},
+		{5, 5, 0, true},
+		{6, 5, 0, true},
+		{4, 20, time.Second, false},
+		{5, 20, 2 * time.Second, false},
+		{6, 20, 4 * time.Second, false},
+		{9, 20, 32 * time.Second, false},
+		{10, 20, time.Minute, false}, // 64 detik, dibatasi MaxDelay
+		{19, 20, time.Minute, false},
+		{20, 20, 0, true},
+		{100, 200, time.Minute, false}, // shift meluap, tetap MaxDelay
+	}
+	for _, tt := range tests {
+		delay, locked := p.penalty(tt.failures, tt.limit)
+		if delay != tt.delay || locked != tt.locked {
+			t.Errorf("penalty(%d, %d) = %v, %v, want %v, %v", tt.failures, tt.limit, delay, locked, tt.delay, tt.locked)
+		}
+	}
+}
+
+func TestLockoutPolicyFromEnv(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "7")
+	t.Setenv("LOGIN_LOCKOUT", "30m")
+	p, err := lockoutPolicyFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.AccountLimit != 7 || p.Lockout != 30*time.Minute || p.IPLimit != 20 || p.FreeFailures != 3 {
+		t.Errorf("lockoutPolicyFromEnv() = %+v", p)
+	}
+	t.Setenv("LOGIN_MAX_FAILURES", "0")
+	if _, err := lockoutPolicyFromEnv(); err == nil {
+		t.Error("LOGIN_MAX_FAILURES=0 accepted")
+	}
+}
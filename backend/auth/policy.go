@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Roles stored in models.User.Role. Team leads are not a role: a member is a
+// lead of the teams whose Lead field points at them. An org admin manages
+// everything inside their organization; an admin can additionally create
+// organizations. Data access for every role stays within the caller's
+// organization.
+const (
+	RoleMember         = "member"
+	RoleProjectManager = "project_manager"
+	RoleManager        = "manager"
+	RoleOrgAdmin       = "org_admin"
+	RoleAdmin          = "admin"
+)
+
+// Permission names an action guarded by the policy.
+type Permission string
+
+const (
+	PermOrgsCreate         Permission = "orgs:create"
+	PermOrgSettings        Permission = "orgs:settings"
+	PermUsersList          Permission = "users:list"
+	PermUsersVerify        Permission = "users:verify"
+	PermUsersUnlock        Permission = "users:unlock"
+	PermUsersManage        Permission = "users:manage"
+	PermAuditRead          Permission = "audit:read"
+	PermServiceAccounts    Permission = "users:service_accounts"
+	PermInvitesManage      Permission = "invitations:manage"
+	PermCheckinsReadAll    Permission = "checkins:read_all"
+	PermCorrectionsApprove Permission = "checkins:approve_corrections"
+	PermLeaveApprove       Permission = "leave:approve"
+	PermTeamsCreate        Permission = "teams:create"
+	PermTeamsUpdate        Permission = "teams:update"
+	PermTeamsDelete        Permission = "teams:delete"
+	PermProjectsCreate     Permission = "projects:create"
+	PermProjectsUpdate     Permission = "projects:update"
+	PermProjectsDelete     Permission = "projects:delete"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleMember: {
+		PermUsersList,
+	},
+	RoleProjectManager: {
+		PermUsersList,
+		PermCheckinsReadAll,
+		PermProjectsCreate,
+		PermProjectsUpdate,
+	},
+	RoleManager: {
+		PermUsersList,
+		PermInvitesManage,
+		PermCheckinsReadAll,
+		PermCorrectionsApprove,
+		PermLeaveApprove,
+		PermTeamsCreate,
+		PermTeamsUpdate,
+		PermTeamsDelete,
+		PermProjectsCreate,
+		PermProjectsUpdate,
+		PermProjectsDelete,
+	},
+	RoleOrgAdmin: {
+		PermOrgSettings,
+		PermUsersList,
+		PermUsersVerify,
+		PermUsersUnlock,
+		PermUsersManage,
+		PermAuditRead,
+		PermServiceAccounts,
+		PermInvitesManage,
+		PermCheckinsReadAll,
+		PermCorrectionsApprove,
+		PermLeaveApprove,
+		PermTeamsCreate,
+		PermTeamsUpdate,
+		PermTeamsDelete,
+		PermProjectsCreate,
+		PermProjectsUpdate,
+		PermProjectsDelete,
+	},
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Can reports whether the principal's role grants perm. Admins are granted
+// everything.
+func (p *Principal) Can(perm Permission) bool {
+	if p == nil {
+		return false
+	}
+	if p.Role == RoleAdmin {
+		return true
+	}
+	for _, granted := range rolePermissions[p.Role] {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// CanGrant reports whether the principal may give role to someone else,
+// for example through an invitation. Only admins grant admin, only admins
+// and org admins grant org_admin, and other roles need a granter who may
+// invite or manage users.
+func (p *Principal) CanGrant(role string) bool {
+	switch role {
+	case RoleAdmin:
+		return p.Role == RoleAdmin
+	case RoleOrgAdmin:
+		return p.Role == RoleAdmin || p.Role == RoleOrgAdmin
+	default:
+		return ValidRole(role) && (p.Can(PermInvitesManage) || p.Can(PermUsersManage))
+	}
+}
+
+// Rule decides whether the caller may proceed. Rules that need the target
+// resource (for example "lead of this team") read it from the request.
+type Rule func(c *fiber.Ctx, p *Principal) (bool, error)
+
+// Has is a Rule satisfied when the caller's role grants perm.
+func Has(perm Permission) Rule {
+	return func(_ *fiber.Ctx, p *Principal) (bool, error) {
+		return p.Can(perm), nil
+	}
+}
+
+// Authorize lets the request through when any of rules allows it and
+// responds 403 otherwise. It must run after Required.
+func Authorize(rules ...Rule) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		p := PrincipalFrom(c)
+		if p == nil {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+		for _, rule := range rules {
+			ok, err := rule(c, p)
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			if ok {
+				return c.Next()
+			}
+		}
+		return Forbidden(c)
+	}
+}
+
+// Forbidden writes the standard 403 response.
+func Forbidden(c *fiber.Ctx) error {
+	return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Forbidden", "code": "forbidden"})
+}
@@ -0,0 +1,62 @@
+package auth
+
+import "testing"
+
+func TestCanGrant(t *testing.T) {
+	tests := []struct {
+		granter string
+		role    string
+		want    bool
+	}{
+		{RoleAdmin, RoleAdmin, true},
+		{RoleAdmin, RoleOrgAdmin, true},
+		{RoleOrgAdmin, RoleAdmin, false},
+		{RoleOrgAdmin, RoleOrgAdmin, true},
+		{RoleOrgAdmin, RoleManager, true},
+		{RoleManager, RoleAdmin, false},
+		{RoleManager, RoleOrgAdmin, false},
+		{RoleManager, RoleManager, true},
+		{RoleManager, RoleMember, true},
+		{RoleMember, RoleProjectManager, false},
+		{RoleMember, RoleMember, false},
+		{RoleProjectManager, RoleMember, false},
+		{RoleProjectManager, RoleProjectManager, false},
+		{RoleOrgAdmin, RoleMember, true},
+		{RoleAdmin, RoleMember, true},
+		{RoleOrgAdmin, "owner", false},
+		{RoleAdmin, "", false},
+	}
+	for _, tt := range tests {
+		p := &Principal{Role: tt.granter}
+		if got := p.CanGrant(tt.role); got != tt.want {
+			t.Errorf("%s CanGrant(%q) = %v, want %v", tt.granter, tt.role, got, tt.want)
+		}
+	}
+}
+
+func TestCan(t *testing.T) {
+	tests := []struct {
+		role string
+		perm Permission
+		want bool
+	}{
+		{RoleAdmin, PermOrgSettings, true},
+		{RoleOrgAdmin, PermOrgSettings, true},
+		{RoleManager, PermOrgSettings, false},
+		{RoleManager, PermTeamsUpdate, true},
+		{RoleManager, PermCorrectionsApprove, true},
+		{RoleMember, PermTeamsUpdate, false},
+		{RoleMember, PermCheckinsReadAll, false},
+		{"unknown", PermUsersList, false},
+	}
+	for _, tt := range tests {
+		p := &Principal{Role: tt.role}
+		if got := p.Can(tt.perm); got != tt.want {
+			t.Errorf("%s Can(%s) = %v, want %v", tt.role, tt.perm, got, tt.want)
+		}
+	}
+	var nobody *Principal
+	if nobody.Can(PermUsersList) {
+		t.Error("nil principal Can(users:list) = true")
+	}
+}
@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// NewSecret returns a random URL-safe secret and the hash to store in its
+// place.
+func NewSecret() (secret, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	secret = base64.RawURLEncoding.EncodeToString(buf)
+	return secret, HashSecret(secret), nil
+}
+
+// HashSecret is the SHA-256 hex digest used to look secrets up without
+// storing them.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// lastSeenInterval limits how often a request bumps Session.LastSeenAt.
+const lastSeenInterval = time.Minute
+
+// ErrInvalidRefreshToken is returned for unknown, expired, revoked or
+// already-rotated refresh tokens.
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// TokenPair is what a client receives on login and on every refresh.
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// EnsureIndexes creates the indexes the session, user token, API key, login
+// attempt and audit stores rely on. Expired documents are removed by MongoDB's TTL
+// monitor.
+func (m *Manager) EnsureIndexes(ctx context.Context) error {
+	_, err := m.sessions.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = m.userTokens.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "purpose", Value: 1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = m.apiKeys.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "keyHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = m.attempts.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return err
+	}
+	return m.audit.EnsureIndexes(ctx)
+}
+
+// StartSession records a new session for user and returns its first token
+// pair.
+func (m *Manager) StartSession(ctx context.Context, user models.User, device, ip string) (*TokenPair, error) {
+	return m.startSession(ctx, user, device, ip, false)
+}
+
+// StartEnrollmentSession is StartSession for a user whose role requires
+// two-factor authentication they have not set up yet. Its tokens only work
+// on routes behind RequiredAllowEnrollment.
+func (m *Manager) StartEnrollmentSession(ctx context.Context, user models.User, device, ip string) (*TokenPair, error) {
+	return m.startSession(ctx, user, device, ip, true)
+}
+
+func (m *Manager) startSession(ctx context.Context, user models.User, device, ip string, enrollOnly bool) (*TokenPair, error) {
+	secret, hash, err := NewSecret()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := models.Session{
+		ID:          primitive.NewObjectID(),
+		UserID:      user.ID,
+		RefreshHash: hash,
+		Device:      device,
+		IP:          ip,
+		CreatedAt:   now,
+		LastSeenAt:  now,
+		ExpiresAt:   now.Add(m.refreshTTL),
+		EnrollOnly:  enrollOnly,
+	}
+	if _, err := m.sessions.InsertOne(ctx, session); err != nil {
+		return nil, err
+	}
+	return m.tokenPair(user, session, secret)
+}
+
+// Refresh exchanges a refresh token for a new pair, rotating the refresh
+// token. Presenting a token that was already rotated revokes the session,
+// since it means the token was copied.
+func (m *Manager) Refresh(ctx context.Context, refreshToken, device, ip string) (*TokenPair, *models.User, error) {
+	sessionID, presented, ok := splitRefreshToken(refreshToken)
+	if !ok {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	secret, hash, err := NewSecret()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	var session models.Session
+	err = m.sessions.FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":         sessionID,
+			"refreshHash": HashSecret(presented),
+			"revokedAt":   bson.M{"$exists": false},
+			"expiresAt":   bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{"refreshHash": hash, "lastSeenAt": now, "device": device, "ip": ip}},
+	).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Either the session is gone or the token was already rotated.
+		_, _ = m.sessions.UpdateOne(ctx,
+			bson.M{"_id": sessionID, "revokedAt": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"revokedAt": now}},
+		)
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var user models.User
+	if err := m.users.FindOne(ctx, bson.M{"_id": session.UserID, "deactivated": bson.M{"$ne": true}}).Decode(&user); err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	pair, err := m.tokenPair(user, session, secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, &user, nil
+}
+
+// RevokeSession revokes one of userID's sessions.
+func (m *Manager) RevokeSession(ctx context.Context, userID, sessionID primitive.ObjectID) (bool, error) {
+	res, err := m.sessions.UpdateOne(ctx,
+		bson.M{"_id": sessionID, "userId": userID, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// RevokeAllSessions revokes every session of userID except keep, which may be
+// primitive.NilObjectID to revoke them all.
+func (m *Manager) RevokeAllSessions(ctx context.Context, userID, keep primitive.ObjectID) (int64, error) {
+	filter := bson.M{"userId": userID, "revokedAt": bson.M{"$exists": false}}
+	if !keep.IsZero() {
+		filter["_id"] = bson.M{"$ne": keep}
+	}
+	res, err := m.sessions.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revokedAt": time.Now()}})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// ListSessions returns userID's active sessions, most recently used first.
+func (m *Manager) ListSessions(ctx context.Context, userID primitive.ObjectID) ([]models.Session, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "lastSeenAt", Value: -1}})
+	cur, err := m.sessions.Find(ctx, bson.M{
+		"userId":    userID,
+		"revokedAt": bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	sessions := []models.Session{}
+	if err := cur.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// touchSession reports whether the session is still active and bumps its
+// LastSeenAt at most once per lastSeenInterval.
+func (m *Manager) touchSession(ctx context.Context, sessionID, userID primitive.ObjectID) (bool, error) {
+	now := time.Now()
+	active := bson.M{
+		"_id":       sessionID,
+		"userId":    userID,
+		"revokedAt": bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": now},
+	}
+	stale := bson.M{"lastSeenAt": bson.M{"$lt": now.Add(-lastSeenInterval)}}
+	for k, v := range active {
+		stale[k] = v
+	}
+	res, err := m.sessions.UpdateOne(ctx, stale, bson.M{"$set": bson.M{"lastSeenAt": now}})
+	if err != nil {
+		return false, err
+	}
+	if res.MatchedCount > 0 {
+		return true, nil
+	}
+	count, err := m.sessions.CountDocuments(ctx, active)
+	return count > 0, err
+}
+
+func (m *Manager) tokenPair(user models.User, session models.Session, secret string) (*TokenPair, error) {
+	access, err := m.IssueToken(user, session.ID, session.EnrollOnly)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{AccessToken: access, RefreshToken: session.ID.Hex() + "." + secret}, nil
+}
+
+// Refresh tokens look like "<session id>.<random secret>"; only the hash of
+// the secret is stored.
+func splitRefreshToken(token string) (primitive.ObjectID, string, bool) {
+	idHex, secret, ok := strings.Cut(token, ".")
+	if !ok || secret == "" {
+		return primitive.NilObjectID, "", false
+	}
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return primitive.NilObjectID, "", false
+	}
+	return id, secret, true
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Kunci uji RFC 4226/6238: "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226 Appendix D
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	key := []byte("12345678901234567890")
+	for counter, code := range want {
+		if got := hotp(key, int64(counter)); got != code {
+			t.Errorf("hotp(%d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	// RFC 6238 Appendix B (SHA-1), enam digit terakhir
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		step, ok := VerifyTOTP(rfcSecret, tt.code, time.Unix(tt.unix, 0))
+		if !ok {
+			t.Errorf("VerifyTOTP(%s) at %d rejected", tt.code, tt.unix)
+			continue
+		}
+		if want := tt.unix / totpPeriod; step != want {
+			t.Errorf("VerifyTOTP(%s) at %d step = %d, want %d", tt.code, tt.unix, step, want)
+		}
+	}
+}
+
+func TestVerifyTOTPInput(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		want   bool
+	}{
+		{"exact", rfcSecret, "050471", true},
+		{"spaces", rfcSecret, " 050 471 ", true},
+		{"lowercase secret", strings.ToLower(rfcSecret), "050471", true},
+		{"wrong code", rfcSecret, "050472", false},
+		{"too short", rfcSecret, "50471", false},
+		{"too long", rfcSecret, "0504710", false},
+		{"bad secret", "not base32!", "050471", false},
+	}
+	for _, tt := range tests {
+		if _, got := VerifyTOTP(tt.secret, tt.code, at); got != tt.want {
+			t.Errorf("%s: VerifyTOTP = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTPSkew(t *testing.T) {
+	key, _ := totpEncoding.DecodeString(rfcSecret)
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+	for offset := int64(-3); offset <= 3; offset++ {
+		step, ok := VerifyTOTP(rfcSecret, hotp(key, current+offset), now)
+		want := offset >= -totpSkew && offset <= totpSkew
+		if ok != want {
+			t.Errorf("step offset %d accepted = %v, want %v", offset, ok, want)
+		}
+		if ok && step != current+offset {
+			t.Errorf("step offset %d returned step %d, want %d", offset, step, current+offset)
+		}
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	want := HashRecoveryCode("abcd-efgh")
+	for _, code := range []string{"abcdefgh", "ABCD-EFGH", " abcd efgh", "Ab-Cd-Ef-Gh"} {
+		if got := HashRecoveryCode(code); got != want {
+			t.Errorf("HashRecoveryCode(%q) differs from abcd-efgh", code)
+		}
+	}
+	if HashRecoveryCode("abcd-efgi") == want {
+		t.Error("different codes hash the same")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), recoveryCodeCount)
+	}
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if len(code) != 9 || code[4] != '-' {
+			t.Errorf("code %q is not xxxx-xxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q repeated", code)
+		}
+		seen[code] = true
+		if hashes[i] == code || hashes[i] != HashRecoveryCode(code) {
+			t.Errorf("hash %d does not match its code", i)
+		}
+	}
+
+	// Kode yang dipakai dihapus ($pull); hanya kode itu yang berhenti berlaku
+	used, ok := MatchRecoveryCode(hashes, strings.ToUpper(codes[3]))
+	if !ok || used != hashes[3] {
+		t.Fatalf("MatchRecoveryCode(codes[3]) = %q, %v", used, ok)
+	}
+	remaining := append(append([]string{}, hashes[:3]...), hashes[4:]...)
+	if _, ok := MatchRecoveryCode(remaining, codes[3]); ok {
+		t.Error("used recovery code accepted again")
+	}
+	if _, ok := MatchRecoveryCode(remaining, codes[4]); !ok {
+		t.Error("unused recovery code rejected")
+	}
+	if _, ok := MatchRecoveryCode(hashes, "zzzz-zzzz"); ok {
+		t.Error("unknown recovery code accepted")
+	}
+}
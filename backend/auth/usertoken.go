@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Purposes of single-use user tokens.
+const (
+	PurposePasswordReset = "password_reset"
+	PurposeVerifyEmail   = "verify_email"
+)
+
+// ErrInvalidUserToken is returned for unknown, expired or already used
+// tokens.
+var ErrInvalidUserToken = errors.New("invalid or expired token")
+
+// IssueUserToken creates a single-use token for userID valid for ttl.
+// Earlier unused tokens with the same purpose stop working.
+func (m *Manager) IssueUserToken(ctx context.Context, userID primitive.ObjectID, purpose string, ttl time.Duration) (string, error) {
+	secret, hash, err := NewSecret()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	_, err = m.userTokens.UpdateMany(ctx,
+		bson.M{"userId": userID, "purpose": purpose, "usedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"usedAt": now}},
+	)
+	if err != nil {
+		return "", err
+	}
+	_, err = m.userTokens.InsertOne(ctx, models.UserToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// ConsumeUserToken marks token as used and returns the user it was issued
+// to.
+func (m *Manager) ConsumeUserToken(ctx context.Context, token, purpose string) (primitive.ObjectID, error) {
+	if token == "" {
+		return primitive.NilObjectID, ErrInvalidUserToken
+	}
+	now := time.Now()
+	var ut models.UserToken
+	err := m.userTokens.FindOneAndUpdate(ctx,
+		bson.M{
+			"tokenHash": HashSecret(token),
+			"purpose":   purpose,
+			"usedAt":    bson.M{"$exists": false},
+			"expiresAt": bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{"usedAt": now}},
+	).Decode(&ut)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return primitive.NilObjectID, ErrInvalidUserToken
+	}
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return ut.UserID, nil
+}
+
+// LastUserTokenAt returns when the newest token with purpose was issued to
+// userID, or the zero time if none was.
+func (m *Manager) LastUserTokenAt(ctx context.Context, userID primitive.ObjectID, purpose string) (time.Time, error) {
+	var ut models.UserToken
+	err := m.userTokens.FindOne(ctx,
+		bson.M{"userId": userID, "purpose": purpose},
+		options.FindOne().SetSort(bson.D{{Key: "createdAt", Value: -1}}),
+	).Decode(&ut)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return time.Time{}, nil
+	}
+	return ut.CreatedAt, err
+}
@@ -0,0 +1,68 @@
+// Command migrate-selfies moves base64 selfies stored inline in check-ins
+// into the blob store configured for the API (STORAGE_DRIVER and friends).
+// It is resumable and safe to run while the API is live.
+//
+//	go run ./cmd/migrate-selfies -dry-run
+//	go run ./cmd/migrate-selfies -batch 100
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"backend/routes"
+	"backend/storage"
+
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	batch := flag.Int64("batch", 50, "check-ins fetched per batch")
+	limit := flag.Int64("limit", 0, "stop after this many check-ins (0 = all)")
+	dryRun := flag.Bool("dry-run", false, "decode and validate selfies without writing anything")
+	retryFailed := flag.Bool("retry-failed", false, "retry check-ins a previous run marked as failed")
+	flag.Parse()
+
+	_ = godotenv.Load()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(os.Getenv("MONGODB_URI")))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+	db := client.Database("wellness")
+
+	store, err := storage.FromEnv(os.Getenv("JWT_SECRET"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	start := time.Now()
+	report, err := routes.MigrateInlineSelfies(ctx, db, store, routes.SelfieMigrationOptions{
+		BatchSize:   *batch,
+		Limit:       *limit,
+		DryRun:      *dryRun,
+		RetryFailed: *retryFailed,
+		Progress: func(r routes.SelfieMigrationReport) {
+			log.Printf("scanned %d, migrated %d, failed %d, skipped %d, %.1f MB moved",
+				r.Scanned, r.Migrated, r.Failed, r.Skipped, float64(r.BytesMoved)/(1<<20))
+		},
+	})
+	for _, f := range report.Failures {
+		log.Printf("failed %s: %s", f.CheckinID.Hex(), f.Reason)
+	}
+	log.Printf("done in %s: scanned %d, migrated %d, failed %d, skipped %d, %.1f MB moved (dry run: %v)",
+		time.Since(start).Round(time.Second), report.Scanned, report.Migrated, report.Failed, report.Skipped,
+		float64(report.BytesMoved)/(1<<20), *dryRun)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,41 @@
+// Command mock-oidc is a minimal OpenID Connect provider for trying single
+// sign-on locally. It signs every user in without a prompt as the identity
+// given by its flags; login_hint overrides the email.
+//
+//	go run ./cmd/mock-oidc -email alice@example.com -groups hr-admins
+//
+// Then start the API with OIDC_ISSUER=http://localhost:9400,
+// OIDC_CLIENT_ID=wellbeing and OIDC_CLIENT_SECRET=secret.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"backend/oidc/oidctest"
+)
+
+func main() {
+	addr := flag.String("addr", ":9400", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9400", "issuer URL advertised in discovery and tokens")
+	clientID := flag.String("client-id", "wellbeing", "accepted client id")
+	clientSecret := flag.String("client-secret", "secret", "accepted client secret")
+	email := flag.String("email", "sso.user@example.com", "email of the signed-in user")
+	name := flag.String("name", "SSO User", "name of the signed-in user")
+	groups := flag.String("groups", "", "comma-separated groups claim")
+	flag.Parse()
+
+	provider, err := oidctest.New(*issuer, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatal(err)
+	}
+	provider.Email, provider.Name = *email, *name
+	if *groups != "" {
+		provider.Groups = strings.Split(*groups, ",")
+	}
+
+	log.Printf("Mock OIDC provider for %s at %s", *email, *issuer)
+	log.Fatal(http.ListenAndServe(*addr, provider.Handler()))
+}
@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogMailer is for local development: it writes each message to an .eml file
+// in Dir, or prints it to the server log when Dir is empty.
+type LogMailer struct {
+	From string
+	Dir  string
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	data := render(m.From, msg)
+	if m.Dir == "" {
+		log.Printf("mail to %s:\n%s", msg.To, data)
+		return nil
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s.eml", time.Now().UTC().Format("20060102T150405.000000000"))
+	return os.WriteFile(filepath.Join(m.Dir, name), data, 0o600)
+}
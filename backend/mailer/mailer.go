@@ -0,0 +1,76 @@
+// Package mailer sends transactional email such as password reset links.
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// FromEnv picks the implementation named by MAIL_DRIVER: "smtp" uses
+// SMTP_HOST, SMTP_PORT, SMTP_USERNAME and SMTP_PASSWORD; "log" (the default)
+// writes messages to MAIL_DIR, or to the server log when MAIL_DIR is empty.
+// MAIL_FROM sets the sender for both.
+func FromEnv() (Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Wellbeing Check <no-reply@localhost>"
+	}
+	switch driver := strings.ToLower(os.Getenv("MAIL_DRIVER")); driver {
+	case "", "log":
+		return &LogMailer{From: from, Dir: os.Getenv("MAIL_DIR")}, nil
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("mailer: SMTP_HOST is not set")
+		}
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return &SMTPMailer{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}, nil
+	default:
+		return nil, fmt.Errorf("mailer: unknown MAIL_DRIVER %q", driver)
+	}
+}
+
+// render builds an RFC 5322 message with CRLF line endings.
+func render(from string, msg Message) []byte {
+	var b strings.Builder
+	header := func(k, v string) {
+		b.WriteString(k + ": " + stripCRLF(v) + "\r\n")
+	}
+	header("From", from)
+	header("To", msg.To)
+	header("Subject", msg.Subject)
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=UTF-8")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func stripCRLF(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
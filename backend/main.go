@@ -2,11 +2,16 @@
 package main
 
 import (
+	"backend/auth"
+	"backend/mailer"
+	"backend/oidc"
 	"backend/routes"
+	"backend/storage"
 	"context"
 	"log"
 	"os"
 	"time"
+	_ "time/tzdata" // zona waktu organisasi/pengguna tetap valid di image alpine
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -20,9 +25,9 @@ var mongoClient *mongo.Client
 func main() {
 	// Load .env
 	_ = godotenv.Load()
-	log.Println("JWT_SECRET:", os.Getenv("JWT_SECRET"))
 
-	app := fiber.New()
+	// Batas body cukup untuk selfie base64 (maks. 5 MB sebelum encoding)
+	app := fiber.New(fiber.Config{BodyLimit: 8 << 20})
 	app.Use(cors.New())
 
 	// MongoDB connection
@@ -35,10 +40,85 @@ func main() {
 	mongoClient = client
 	db := client.Database("wellness") // Ganti sesuai nama database Anda
 
-	routes.RegisterCheckinRoutes(app, db)
-	routes.RegisterUserRoutes(app, db)
-	routes.RegisterProjectRoutes(app, db)
-	routes.RegisterTeamRoutes(app, db)
+	authn, err := auth.NewManager(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := authn.EnsureIndexes(ctx); err != nil {
+		log.Fatal(err)
+	}
+	if err := routes.MigrateOrganizations(ctx, db); err != nil {
+		log.Fatal(err)
+	}
+	if err := routes.BackfillEmailVerified(ctx, db); err != nil {
+		log.Fatal(err)
+	}
+	if err := routes.EnsureCheckinIndexes(ctx, db); err != nil {
+		log.Fatal(err)
+	}
+	if err := routes.NormalizeMoods(ctx, db); err != nil {
+		log.Fatal(err)
+	}
+	if err := routes.EnsureMoodScaleIndexes(ctx, db); err != nil {
+		log.Fatal(err)
+	}
+	if err := routes.EnsureCorrectionIndexes(ctx, db); err != nil {
+		log.Fatal(err)
+	}
+	if err := routes.EnsureIdempotencyIndexes(ctx, db); err != nil {
+		log.Fatal(err)
+	}
+	if err := routes.EnsureAttendanceIndexes(ctx, db); err != nil {
+		log.Fatal(err)
+	}
+	if err := routes.EnsureSSOIndexes(ctx, db); err != nil {
+		log.Fatal(err)
+	}
+	mail, err := mailer.FromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	sso, err := oidc.FromEnv(routes.SSORedirectURL())
+	if err != nil {
+		log.Fatal(err)
+	}
+	store, err := storage.FromEnv(os.Getenv("JWT_SECRET"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	urlTTL, err := routes.SignedURLTTL()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := routes.StartAbsenceJob(context.Background(), db); err != nil {
+		log.Fatal(err)
+	}
+	if err := routes.StartSelfiePurgeJob(context.Background(), db, store); err != nil {
+		log.Fatal(err)
+	}
+
+	routes.RegisterCheckinRoutes(app, db, authn, store, urlTTL)
+	routes.RegisterCheckinSyncRoutes(app, db, authn, store, urlTTL)
+	routes.RegisterCorrectionRoutes(app, db, authn, store, urlTTL)
+	routes.RegisterUploadRoutes(app, db, authn, store, urlTTL)
+	routes.RegisterMoodRoutes(app, authn)
+	routes.RegisterMoodScaleRoutes(app, db, authn)
+	routes.RegisterAttendanceRoutes(app, db, authn)
+	routes.RegisterLeaveRoutes(app, db, authn)
+	routes.RegisterHolidayRoutes(app, db, authn)
+	routes.RegisterUserRoutes(app, db, authn, mail)
+	routes.RegisterVerificationRoutes(app, db, authn, mail)
+	routes.RegisterInvitationRoutes(app, db, authn, mail)
+	routes.RegisterSessionRoutes(app, authn)
+	routes.RegisterSSORoutes(app, db, authn, sso)
+	routes.RegisterTwoFactorRoutes(app, db, authn)
+	routes.RegisterAPIKeyRoutes(app, db, authn)
+	routes.RegisterPasswordRoutes(app, db, authn, mail)
+	routes.RegisterOrganizationRoutes(app, db, authn)
+	routes.RegisterRetentionRoutes(app, db, authn, store)
+	routes.RegisterAdminRoutes(app, db, authn, mail)
+	routes.RegisterProjectRoutes(app, db, authn)
+	routes.RegisterTeamRoutes(app, db, authn)
 
 	app.Get("/api/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"status": "ok"})
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Check-in types accepted by POST /api/checkins.
+const (
+	CheckinTypeIn  = "checkin"
+	CheckinTypeOut = "checkout"
+)
+
+// Check-in statuses.
+const (
+	CheckinStatusPresent = "present"
+	CheckinStatusAbsent  = "absent"
+)
+
+// Attendance states.
+const (
+	AttendanceOpen     = "open"     // checked in, not yet out
+	AttendanceComplete = "complete" // checked in and out
+	AttendanceAbsent   = "absent"   // no check-in by the cutoff on a working day
+)
+
+// Attendance is one user's working day. Date is the calendar day of the
+// check-in in the user's timezone, and there is at most one record per user
+// and date. For absences CheckinID points at the generated check-in with
+// status "absent" that reports count.
+type Attendance struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	OrgID         primitive.ObjectID  `bson:"orgId" json:"orgId"`
+	UserID        primitive.ObjectID  `bson:"userId" json:"userId"`
+	Date          string              `bson:"date" json:"date"` // YYYY-MM-DD
+	Timezone      string              `bson:"timezone" json:"timezone"`
+	Status        string              `bson:"status" json:"status"`
+	CheckinID     *primitive.ObjectID `bson:"checkinId,omitempty" json:"checkinId,omitempty"`
+	CheckinAt     *time.Time          `bson:"checkinAt,omitempty" json:"checkinAt,omitempty"`
+	CheckoutID    *primitive.ObjectID `bson:"checkoutId,omitempty" json:"checkoutId,omitempty"`
+	CheckoutAt    *time.Time          `bson:"checkoutAt,omitempty" json:"checkoutAt,omitempty"`
+	WorkedSeconds int64               `bson:"workedSeconds" json:"workedSeconds"`
+	CreatedAt     time.Time           `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time           `bson:"updatedAt" json:"updatedAt"`
+}
+
+func (a *Attendance) SetOrgID(id primitive.ObjectID) { a.OrgID = id }
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditEntry records a security-relevant event such as a lockout or a role
+// change. ActorID is empty for events the system raised itself.
+type AuditEntry struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	OrgID     primitive.ObjectID  `bson:"orgId,omitempty" json:"orgId,omitempty"`
+	Action    string              `bson:"action" json:"action"`
+	ActorID   *primitive.ObjectID `bson:"actorId,omitempty" json:"actorId,omitempty"`
+	TargetID  *primitive.ObjectID `bson:"targetId,omitempty" json:"targetId,omitempty"`
+	IP        string              `bson:"ip,omitempty" json:"ip,omitempty"`
+	Details   map[string]any      `bson:"details,omitempty" json:"details,omitempty"`
+	CreatedAt time.Time           `bson:"createdAt" json:"createdAt"`
+}
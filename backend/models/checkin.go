@@ -7,17 +7,40 @@ import (
 )
 
 type Checkin struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID      primitive.ObjectID `bson:"userId" json:"userId"`
-	Type        string             `bson:"type" json:"type"` // checkin/checkout
-	Mood        string             `bson:"mood" json:"mood"`
-	SelfieURL   string             `bson:"selfieUrl" json:"selfieUrl"`
-	Description string             `bson:"description" json:"description"`
-	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
-	FaceResult  *FaceResult        `bson:"faceResult,omitempty" json:"faceResult,omitempty"`
-	Status      string             `bson:"status" json:"status"` // present/absent
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrgID  primitive.ObjectID `bson:"orgId" json:"orgId"`
+	UserID primitive.ObjectID `bson:"userId" json:"userId"`
+	Type   string             `bson:"type" json:"type"` // checkin/checkout
+	Mood   string             `bson:"mood" json:"mood"`
+	// MoodScore is the valence of Mood, from -1 to 1.
+	MoodScore *int `bson:"moodScore,omitempty" json:"moodScore,omitempty"`
+	// MoodAnswer holds the answers when the organization uses a mood scale.
+	MoodAnswer *MoodAnswer `bson:"moodAnswer,omitempty" json:"moodAnswer,omitempty"`
+	SelfieURL  string      `bson:"selfieUrl" json:"selfieUrl"`
+	// Selfies in the blob store are referenced by key; responses carry
+	// signed URLs in SelfieURL and SelfieThumbnailURL instead.
+	SelfieID           *primitive.ObjectID `bson:"selfieId,omitempty" json:"selfieId,omitempty"`
+	SelfieKey          string              `bson:"selfieKey,omitempty" json:"-"`
+	SelfieThumbKey     string              `bson:"selfieThumbKey,omitempty" json:"-"`
+	SelfieThumbnailURL string              `bson:"-" json:"selfieThumbnailUrl,omitempty"`
+	// SelfiePurgedAt is set when the retention purger removed the selfie.
+	SelfiePurgedAt *time.Time  `bson:"selfiePurgedAt,omitempty" json:"selfiePurgedAt,omitempty"`
+	Description    string      `bson:"description" json:"description"`
+	CreatedAt      time.Time   `bson:"createdAt" json:"createdAt"`
+	FaceResult     *FaceResult `bson:"faceResult,omitempty" json:"faceResult,omitempty"`
+	Status         string      `bson:"status" json:"status"` // present/absent
+	// EditedAt is set when the check-in was edited or corrected;
+	// CorrectionID when an approved correction added it afterwards.
+	EditedAt     *time.Time          `bson:"editedAt,omitempty" json:"editedAt,omitempty"`
+	CorrectionID *primitive.ObjectID `bson:"correctionId,omitempty" json:"correctionId,omitempty"`
+	// ClientID is the id an offline client gave the check-in; ReceivedAt is
+	// when the server got it, while CreatedAt is the client's timestamp.
+	ClientID   string     `bson:"clientId,omitempty" json:"clientId,omitempty"`
+	ReceivedAt *time.Time `bson:"receivedAt,omitempty" json:"receivedAt,omitempty"`
 }
 
+func (c *Checkin) SetOrgID(id primitive.ObjectID) { c.OrgID = id }
+
 type FaceResult struct {
 	Gender     string  `bson:"gender" json:"gender"`
 	Age        float64 `bson:"age" json:"age"`
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Correction request states.
+const (
+	CorrectionPending  = "pending"
+	CorrectionApproved = "approved"
+	CorrectionRejected = "rejected"
+)
+
+// CheckinCorrection asks a team lead to fix a check-in after the edit
+// window: change the time or description of CheckinID, or, without
+// CheckinID, add a forgotten check-in or checkout of Type at At.
+type CheckinCorrection struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	OrgID       primitive.ObjectID  `bson:"orgId" json:"orgId"`
+	UserID      primitive.ObjectID  `bson:"userId" json:"userId"`
+	CheckinID   *primitive.ObjectID `bson:"checkinId,omitempty" json:"checkinId,omitempty"`
+	Type        string              `bson:"type" json:"type"` // checkin/checkout
+	At          *time.Time          `bson:"at,omitempty" json:"at,omitempty"`
+	Description *string             `bson:"description,omitempty" json:"description,omitempty"`
+	Reason      string              `bson:"reason" json:"reason"`
+	Status      string              `bson:"status" json:"status"`
+	ReviewedBy  *primitive.ObjectID `bson:"reviewedBy,omitempty" json:"reviewedBy,omitempty"`
+	ReviewedAt  *time.Time          `bson:"reviewedAt,omitempty" json:"reviewedAt,omitempty"`
+	ReviewNote  string              `bson:"reviewNote,omitempty" json:"reviewNote,omitempty"`
+	CreatedAt   time.Time           `bson:"createdAt" json:"createdAt"`
+}
+
+func (c *CheckinCorrection) SetOrgID(id primitive.ObjectID) { c.OrgID = id }
+
+// Revision actions.
+const (
+	RevisionEdited    = "edited"
+	RevisionDeleted   = "deleted"
+	RevisionCorrected = "corrected"
+)
+
+// CheckinRevision keeps a check-in as it was before an edit, deletion or
+// approved correction.
+type CheckinRevision struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	OrgID        primitive.ObjectID  `bson:"orgId" json:"orgId"`
+	CheckinID    primitive.ObjectID  `bson:"checkinId" json:"checkinId"`
+	UserID       primitive.ObjectID  `bson:"userId" json:"userId"`
+	ChangedBy    primitive.ObjectID  `bson:"changedBy" json:"changedBy"`
+	Action       string              `bson:"action" json:"action"`
+	CorrectionID *primitive.ObjectID `bson:"correctionId,omitempty" json:"correctionId,omitempty"`
+	Before       Checkin             `bson:"before" json:"before"`
+	CreatedAt    time.Time           `bson:"createdAt" json:"createdAt"`
+}
+
+func (r *CheckinRevision) SetOrgID(id primitive.ObjectID) { r.OrgID = id }
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Holiday is a non-working day for a whole organization.
+type Holiday struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrgID     primitive.ObjectID `bson:"orgId" json:"orgId"`
+	Date      string             `bson:"date" json:"date"` // YYYY-MM-DD
+	Name      string             `bson:"name" json:"name"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+func (h *Holiday) SetOrgID(id primitive.ObjectID) { h.OrgID = id }
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Idempotency record states.
+const (
+	IdempotencyProcessing = "processing"
+	IdempotencyDone       = "done"
+)
+
+// IdempotencyRecord remembers a request sent with an Idempotency-Key header
+// and the response it got, so a retry with the same key replays the
+// response instead of repeating the request.
+type IdempotencyRecord struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	OrgID       primitive.ObjectID `bson:"orgId"`
+	UserID      primitive.ObjectID `bson:"userId"`
+	Key         string             `bson:"key"`
+	Fingerprint string             `bson:"fingerprint"` // sha256 dari method, path dan body
+	State       string             `bson:"state"`
+	Status      int                `bson:"status,omitempty"`
+	ContentType string             `bson:"contentType,omitempty"`
+	Body        []byte             `bson:"body,omitempty"`
+	CreatedAt   time.Time          `bson:"createdAt"`
+	ExpiresAt   time.Time          `bson:"expiresAt"`
+}
+
+func (r *IdempotencyRecord) SetOrgID(id primitive.ObjectID) { r.OrgID = id }
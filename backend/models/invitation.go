@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Invitation lets someone join with a preset role and teams. Only the hash of
+// the emailed token is stored.
+type Invitation struct {
+	ID         primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	OrgID      primitive.ObjectID   `bson:"orgId" json:"orgId"`
+	Email      string               `bson:"email" json:"email"`
+	Role       string               `bson:"role" json:"role"`
+	TeamIDs    []primitive.ObjectID `bson:"teamIds" json:"teamIds"`
+	TokenHash  string               `bson:"tokenHash" json:"-"`
+	InvitedBy  primitive.ObjectID   `bson:"invitedBy" json:"invitedBy"`
+	CreatedAt  time.Time            `bson:"createdAt" json:"createdAt"`
+	ExpiresAt  time.Time            `bson:"expiresAt" json:"expiresAt"`
+	AcceptedAt *time.Time           `bson:"acceptedAt,omitempty" json:"acceptedAt,omitempty"`
+	AcceptedBy *primitive.ObjectID  `bson:"acceptedBy,omitempty" json:"acceptedBy,omitempty"`
+	RevokedAt  *time.Time           `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+}
+
+func (i *Invitation) SetOrgID(id primitive.ObjectID) { i.OrgID = id }
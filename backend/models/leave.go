@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Leave request states.
+const (
+	LeavePending  = "pending"
+	LeaveApproved = "approved"
+	LeaveRejected = "rejected"
+)
+
+// LeaveRequest asks for time off from StartDate to EndDate inclusive.
+// Approved leave days are never recorded as absences.
+type LeaveRequest struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	OrgID      primitive.ObjectID  `bson:"orgId" json:"orgId"`
+	UserID     primitive.ObjectID  `bson:"userId" json:"userId"`
+	StartDate  string              `bson:"startDate" json:"startDate"` // YYYY-MM-DD
+	EndDate    string              `bson:"endDate" json:"endDate"`
+	Reason     string              `bson:"reason,omitempty" json:"reason,omitempty"`
+	Status     string              `bson:"status" json:"status"`
+	ReviewedBy *primitive.ObjectID `bson:"reviewedBy,omitempty" json:"reviewedBy,omitempty"`
+	ReviewedAt *time.Time          `bson:"reviewedAt,omitempty" json:"reviewedAt,omitempty"`
+	CreatedAt  time.Time           `bson:"createdAt" json:"createdAt"`
+}
+
+func (l *LeaveRequest) SetOrgID(id primitive.ObjectID) { l.OrgID = id }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// LoginAttempts counts recent failed logins for one key, either
+// "email:<address>" or "ip:<address>". Documents expire on their own once
+// the failure window and any lockout have passed.
+type LoginAttempts struct {
+	Key           string     `bson:"_id" json:"key"`
+	Failures      int        `bson:"failures" json:"failures"`
+	LastFailureAt time.Time  `bson:"lastFailureAt" json:"lastFailureAt"`
+	NextAttemptAt *time.Time `bson:"nextAttemptAt,omitempty" json:"nextAttemptAt,omitempty"`
+	LockedUntil   *time.Time `bson:"lockedUntil,omitempty" json:"lockedUntil,omitempty"`
+	ExpiresAt     time.Time  `bson:"expiresAt" json:"expiresAt"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of extra questions on a mood scale.
+const (
+	QuestionSlider = "slider"
+	QuestionChoice = "choice"
+	QuestionText   = "text"
+)
+
+// MoodScale is an organization's check-in questionnaire: the options for
+// the main mood question plus optional extra questions. At most one scale
+// per organization is active; without one the canonical moods are used.
+// Editing a scale bumps Version. Check-ins copy the labels they were
+// answered with, so they stay readable after the scale changes.
+type MoodScale struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrgID     primitive.ObjectID `bson:"orgId" json:"orgId"`
+	Name      string             `bson:"name" json:"name"`
+	Version   int                `bson:"version" json:"version"`
+	Options   []MoodOption       `bson:"options" json:"options"`
+	Questions []MoodQuestion     `bson:"questions" json:"questions"`
+	Active    bool               `bson:"active" json:"active"`
+	Archived  bool               `bson:"archived" json:"archived"`
+	CreatedBy primitive.ObjectID `bson:"createdBy" json:"createdBy"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+func (s *MoodScale) SetOrgID(id primitive.ObjectID) { s.OrgID = id }
+
+// MoodOption is one answer on a scale. Mood optionally maps it to a
+// canonical mood (Happy, Neutral, Stressed) so reports built on Mood and
+// MoodScore keep working.
+type MoodOption struct {
+	Value int    `bson:"value" json:"value"`
+	Label string `bson:"label" json:"label"`
+	Emoji string `bson:"emoji,omitempty" json:"emoji,omitempty"`
+	Color string `bson:"color,omitempty" json:"color,omitempty"` // #RRGGBB
+	Mood  string `bson:"mood,omitempty" json:"mood,omitempty"`
+}
+
+// MoodQuestion is an extra question, such as an energy or stress slider.
+type MoodQuestion struct {
+	Key      string       `bson:"key" json:"key"`
+	Label    string       `bson:"label" json:"label"`
+	Kind     string       `bson:"kind" json:"kind"` // slider/choice/text
+	Min      int          `bson:"min,omitempty" json:"min,omitempty"`
+	Max      int          `bson:"max,omitempty" json:"max,omitempty"`
+	Options  []MoodOption `bson:"options,omitempty" json:"options,omitempty"`
+	Required bool         `bson:"required" json:"required"`
+}
+
+// MoodAnswer is a check-in's answers to a mood scale, with the labels as
+// they were when answered.
+type MoodAnswer struct {
+	ScaleID      primitive.ObjectID `bson:"scaleId" json:"scaleId"`
+	ScaleVersion int                `bson:"scaleVersion" json:"scaleVersion"`
+	Value        int                `bson:"value" json:"value"`
+	Label        string             `bson:"label" json:"label"`
+	Emoji        string             `bson:"emoji,omitempty" json:"emoji,omitempty"`
+	Color        string             `bson:"color,omitempty" json:"color,omitempty"`
+	Answers      []QuestionAnswer   `bson:"answers,omitempty" json:"answers,omitempty"`
+}
+
+type QuestionAnswer struct {
+	Key      string `bson:"key" json:"key"`
+	Question string `bson:"question" json:"question"`
+	Value    *int   `bson:"value,omitempty" json:"value,omitempty"`
+	Label    string `bson:"label,omitempty" json:"label,omitempty"` // pilihan yang dipilih
+	Text     string `bson:"text,omitempty" json:"text,omitempty"`
+}
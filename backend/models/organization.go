@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Organization is a tenant. Users, teams, projects and check-ins belong to
+// exactly one organization.
+type Organization struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`
+	Slug      string             `bson:"slug" json:"slug"`
+	Settings  OrgSettings        `bson:"settings" json:"settings"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+type OrgSettings struct {
+	Timezone   string `bson:"timezone,omitempty" json:"timezone,omitempty"` // IANA, mis. "Asia/Jakarta"
+	InviteOnly bool   `bson:"inviteOnly" json:"inviteOnly"`
+	// MFARequiredRoles lists roles that must use two-factor authentication.
+	MFARequiredRoles []string `bson:"mfaRequiredRoles,omitempty" json:"mfaRequiredRoles,omitempty"`
+	// WorkingDays are the weekdays (0 = Sunday) on which a missing check-in
+	// counts as an absence. Empty means Monday to Friday.
+	WorkingDays []time.Weekday `bson:"workingDays,omitempty" json:"workingDays,omitempty"`
+	// AbsenceCutoff is the local time ("HH:MM") after which users without a
+	// check-in are marked absent. Empty means ABSENCE_CUTOFF.
+	AbsenceCutoff string `bson:"absenceCutoff,omitempty" json:"absenceCutoff,omitempty"`
+	// SelfieRetentionDays is how long check-in selfies are kept before the
+	// purger deletes them. Zero keeps them forever.
+	SelfieRetentionDays int `bson:"selfieRetentionDays,omitempty" json:"selfieRetentionDays,omitempty"`
+	// AnonymizeFaceData also strips the estimated age and gender from
+	// check-ins whose selfie has been purged.
+	AnonymizeFaceData bool `bson:"anonymizeFaceData,omitempty" json:"anonymizeFaceData,omitempty"`
+}
@@ -8,6 +8,7 @@ import (
 
 type Project struct {
 	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	OrgID       primitive.ObjectID   `bson:"orgId" json:"orgId"`
 	Name        string               `bson:"name" json:"name"`
 	Description string               `bson:"description,omitempty" json:"description,omitempty"`
 	StartDate   *time.Time           `bson:"startDate,omitempty" json:"startDate,omitempty"`
@@ -15,3 +16,5 @@ type Project struct {
 	Teams       []primitive.ObjectID `bson:"teams" json:"teams"`
 	CreatedAt   time.Time            `bson:"createdAt" json:"createdAt"`
 }
+
+func (p *Project) SetOrgID(id primitive.ObjectID) { p.OrgID = id }
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SelfiePurge reports one run of the selfie retention purger for an
+// organization. Dry runs count what would have been removed without
+// touching anything.
+type SelfiePurge struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	OrgID         primitive.ObjectID  `bson:"orgId" json:"orgId"`
+	DryRun        bool                `bson:"dryRun" json:"dryRun"`
+	TriggeredBy   *primitive.ObjectID `bson:"triggeredBy,omitempty" json:"triggeredBy,omitempty"` // kosong = job
+	RetentionDays int                 `bson:"retentionDays" json:"retentionDays"`
+	Cutoff        time.Time           `bson:"cutoff" json:"cutoff"`
+	// SelfiesDeleted counts uploaded selfies removed from the blob store,
+	// InlineSelfiesCleared legacy base64 selfies cleared from check-ins.
+	SelfiesDeleted        int64  `bson:"selfiesDeleted" json:"selfiesDeleted"`
+	BytesDeleted          int64  `bson:"bytesDeleted" json:"bytesDeleted"`
+	InlineSelfiesCleared  int64  `bson:"inlineSelfiesCleared" json:"inlineSelfiesCleared"`
+	FaceResultsAnonymized int64  `bson:"faceResultsAnonymized" json:"faceResultsAnonymized"`
+	Failed                int64  `bson:"failed" json:"failed"`
+	Error                 string `bson:"error,omitempty" json:"error,omitempty"`
+	// CheckinIDs lists affected check-ins, up to a limit.
+	CheckinIDs []primitive.ObjectID `bson:"checkinIds" json:"checkinIds"`
+	Truncated  bool                 `bson:"truncated" json:"truncated"`
+	StartedAt  time.Time            `bson:"startedAt" json:"startedAt"`
+	FinishedAt time.Time            `bson:"finishedAt" json:"finishedAt"`
+}
+
+func (p *SelfiePurge) SetOrgID(id primitive.ObjectID) { p.OrgID = id }
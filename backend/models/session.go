@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is one signed-in device. Access tokens carry the session ID so a
+// revoked session stops working before its tokens expire.
+type Session struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"userId" json:"userId"`
+	RefreshHash string             `bson:"refreshHash" json:"-"`
+	Device      string             `bson:"device" json:"device"`
+	IP          string             `bson:"ip" json:"ip"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	LastSeenAt  time.Time          `bson:"lastSeenAt" json:"lastSeenAt"`
+	ExpiresAt   time.Time          `bson:"expiresAt" json:"expiresAt"`
+	RevokedAt   *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	EnrollOnly  bool               `bson:"enrollOnly,omitempty" json:"enrollOnly,omitempty"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// SSOState holds what the API needs to finish one single sign-on attempt:
+// the nonce expected in the ID token and the PKCE verifier for the code
+// exchange. The document is keyed by the hash of the state parameter and
+// is deleted when the callback consumes it.
+type SSOState struct {
+	StateHash    string    `bson:"_id"`
+	Nonce        string    `bson:"nonce"`
+	CodeVerifier string    `bson:"codeVerifier"`
+	CreatedAt    time.Time `bson:"createdAt"`
+	ExpiresAt    time.Time `bson:"expiresAt"`
+}
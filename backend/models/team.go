@@ -8,9 +8,12 @@ import (
 
 type Team struct {
 	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	OrgID       primitive.ObjectID   `bson:"orgId" json:"orgId"`
 	Name        string               `bson:"name" json:"name"`
 	Description string               `bson:"description,omitempty" json:"description,omitempty"`
 	Members     []primitive.ObjectID `bson:"members" json:"members"`
 	Lead        primitive.ObjectID   `bson:"lead" json:"lead"`
 	CreatedAt   time.Time            `bson:"createdAt" json:"createdAt"`
 }
+
+func (t *Team) SetOrgID(id primitive.ObjectID) { t.OrgID = id }
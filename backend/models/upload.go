@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Upload is an image kept in the blob store, such as a check-in selfie.
+// CheckinID is set once a check-in uses it.
+type Upload struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	OrgID        primitive.ObjectID  `bson:"orgId" json:"orgId"`
+	UserID       primitive.ObjectID  `bson:"userId" json:"userId"`
+	Key          string              `bson:"key" json:"-"`
+	ThumbnailKey string              `bson:"thumbnailKey" json:"-"`
+	ContentType  string              `bson:"contentType" json:"contentType"`
+	Size         int                 `bson:"size" json:"size"`
+	Width        int                 `bson:"width" json:"width"`
+	Height       int                 `bson:"height" json:"height"`
+	CheckinID    *primitive.ObjectID `bson:"checkinId,omitempty" json:"checkinId,omitempty"`
+	CreatedAt    time.Time           `bson:"createdAt" json:"createdAt"`
+}
+
+func (u *Upload) SetOrgID(id primitive.ObjectID) { u.OrgID = id }
@@ -1,14 +1,46 @@
 package models
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type User struct {
 	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrgID    primitive.ObjectID `bson:"orgId" json:"orgId"`
 	Name     string             `bson:"name" json:"name"`
 	Email    string             `bson:"email" json:"email"`
 	Password string             `bson:"password" json:"-"` // tidak pernah dikirim ke frontend
 	Avatar   string             `bson:"avatar,omitempty" json:"avatar,omitempty"`
-	Role     string             `bson:"role" json:"role"` // "manager" atau "member"
+	Role     string             `bson:"role" json:"role"`                             // "member", "project_manager", "manager", "org_admin" atau "admin"
+	Timezone string             `bson:"timezone,omitempty" json:"timezone,omitempty"` // kosong = zona waktu organisasi
+
+	// ServiceAccount users have no password and authenticate only with API
+	// keys created by an admin.
+	ServiceAccount bool `bson:"serviceAccount,omitempty" json:"serviceAccount,omitempty"`
+
+	// OIDCSubject links the account to the identity provider's "sub" claim
+	// after its first single sign-on login.
+	OIDCSubject string `bson:"oidcSubject,omitempty" json:"-"`
+
+	// Deactivated accounts cannot log in, and their sessions and API keys
+	// stop working. PasswordResetRequired blocks password login until the
+	// user sets a new password from the emailed reset link.
+	Deactivated           bool       `bson:"deactivated,omitempty" json:"deactivated,omitempty"`
+	DeactivatedAt         *time.Time `bson:"deactivatedAt,omitempty" json:"deactivatedAt,omitempty"`
+	PasswordResetRequired bool       `bson:"passwordResetRequired,omitempty" json:"passwordResetRequired,omitempty"`
+
+	EmailVerified   bool       `bson:"emailVerified" json:"emailVerified"`
+	EmailVerifiedAt *time.Time `bson:"emailVerifiedAt,omitempty" json:"emailVerifiedAt,omitempty"`
+
+	// Two-factor authentication. The pending secret is replaced on every
+	// setup attempt and promoted once a code from it is confirmed.
+	TOTPEnabled       bool     `bson:"totpEnabled" json:"totpEnabled"`
+	TOTPSecret        string   `bson:"totpSecret,omitempty" json:"-"`
+	TOTPPendingSecret string   `bson:"totpPendingSecret,omitempty" json:"-"`
+	TOTPLastStep      int64    `bson:"totpLastStep,omitempty" json:"-"`
+	RecoveryCodes     []string `bson:"recoveryCodes,omitempty" json:"-"` // hash SHA-256
 }
+
+func (u *User) SetOrgID(id primitive.ObjectID) { u.OrgID = id }
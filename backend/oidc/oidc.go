@@ -0,0 +1,370 @@
+// Package oidc implements the relying-party side of the OpenID Connect
+// authorization code flow with PKCE: discovery, the authorization URL, the
+// code exchange and ID token validation against the provider's JWKS.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidIDToken is returned when the ID token fails signature, issuer,
+// audience, expiry or nonce checks.
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+// Config describes the client registration at the identity provider.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	// GroupsClaim names the ID token claim listing the user's groups.
+	GroupsClaim string
+	// RoleMapping is checked in order; the first group the user belongs to
+	// decides the role.
+	RoleMapping []GroupRole
+	// DefaultRole is given to provisioned users matching no group.
+	DefaultRole string
+	// Organization is the slug of the organization new users join.
+	Organization  string
+	AutoProvision bool
+}
+
+// GroupRole maps one IdP group onto an application role.
+type GroupRole struct {
+	Group string
+	Role  string
+}
+
+// Identity is what the application learns about the user from a verified
+// ID token.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider talks to one identity provider. Discovery and keys are fetched
+// lazily and cached, so the API starts even while the provider is down.
+type Provider struct {
+	Config
+	client *http.Client
+
+	mu        sync.Mutex
+	meta      *metadata
+	metaAt    time.Time
+	keys      map[string]any
+	keysAt    time.Time
+	keysError error
+}
+
+const (
+	metadataTTL = time.Hour
+	// minKeyRefresh stops tokens with unknown key ids from hammering the
+	// JWKS endpoint.
+	minKeyRefresh = time.Minute
+)
+
+// New returns a provider for cfg.
+func New(cfg Config) *Provider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	return &Provider{Config: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// FromEnv builds a provider from OIDC_* variables. It returns nil when
+// OIDC_ISSUER is unset, meaning single sign-on is disabled.
+func FromEnv(defaultRedirect string) (*Provider, error) {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil, nil
+	}
+	cfg := Config{
+		Issuer:        issuer,
+		ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		GroupsClaim:   os.Getenv("OIDC_GROUPS_CLAIM"),
+		DefaultRole:   os.Getenv("OIDC_DEFAULT_ROLE"),
+		Organization:  os.Getenv("OIDC_ORGANIZATION"),
+		AutoProvision: os.Getenv("OIDC_AUTO_PROVISION") != "false",
+	}
+	if cfg.ClientID == "" {
+		return nil, errors.New("OIDC_CLIENT_ID is required when OIDC_ISSUER is set")
+	}
+	if cfg.RedirectURL == "" {
+		cfg.RedirectURL = defaultRedirect
+	}
+	if v := os.Getenv("OIDC_SCOPES"); v != "" {
+		cfg.Scopes = strings.Fields(strings.ReplaceAll(v, ",", " "))
+	}
+	// OIDC_ROLE_MAPPING="hr-admins=org_admin,team-leads=manager"
+	for _, pair := range strings.Split(os.Getenv("OIDC_ROLE_MAPPING"), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		if !ok || group == "" || role == "" {
+			return nil, fmt.Errorf("invalid OIDC_ROLE_MAPPING entry %q", pair)
+		}
+		cfg.RoleMapping = append(cfg.RoleMapping, GroupRole{Group: strings.TrimSpace(group), Role: strings.TrimSpace(role)})
+	}
+	return New(cfg), nil
+}
+
+// RoleFor returns the mapped role for groups, or "" when none match.
+func (p *Provider) RoleFor(groups []string) string {
+	for _, m := range p.RoleMapping {
+		for _, g := range groups {
+			if g == m.Group {
+				return m.Role
+			}
+		}
+	}
+	return ""
+}
+
+// PKCEChallenge returns the S256 code challenge for verifier.
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the provider URL the browser is sent to.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {p.RedirectURL},
+		"scope":                 {strings.Join(p.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange trades an authorization code for tokens and returns the raw ID
+// token.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	if p.ClientSecret == "" {
+		form.Set("client_id", p.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request: %w", err)
+	}
+	defer res.Body.Close()
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("token response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("token endpoint returned %d: %s %s", res.StatusCode, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+	return body.IDToken, nil
+}
+
+// Verify validates rawIDToken and returns the identity it asserts.
+func (p *Provider) Verify(ctx context.Context, rawIDToken, nonce string) (*Identity, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	// Token untuk beberapa audience harus ditujukan (azp) ke client ini
+	if aud, _ := claims.GetAudience(); len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != p.ClientID {
+			return nil, fmt.Errorf("%w: authorized party mismatch", ErrInvalidIDToken)
+		}
+	}
+	id := &Identity{}
+	id.Subject, _ = claims["sub"].(string)
+	id.Email, _ = claims["email"].(string)
+	id.Name, _ = claims["name"].(string)
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		id.EmailVerified = v
+	case string:
+		id.EmailVerified = v == "true"
+	}
+	switch v := claims[p.GroupsClaim].(type) {
+	case string:
+		id.Groups = []string{v}
+	case []any:
+		for _, g := range v {
+			if s, ok := g.(string); ok {
+				id.Groups = append(id.Groups, s)
+			}
+		}
+	}
+	if id.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	return id, nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil && time.Since(p.metaAt) < metadataTTL {
+		return p.meta, nil
+	}
+	var meta metadata
+	if err := p.getJSON(ctx, p.Issuer+"/.well-known/openid-configuration", &meta); err != nil {
+		if p.meta != nil {
+			return p.meta, nil
+		}
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimSuffix(meta.Issuer, "/") != p.Issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match %q", meta.Issuer, p.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: incomplete provider metadata")
+	}
+	p.meta, p.metaAt = &meta, time.Now()
+	return p.meta, nil
+}
+
+// key returns the verification key for kid, refetching the JWKS when the
+// provider has rotated keys.
+func (p *Provider) key(ctx context.Context, kid string) (any, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if k, ok := p.lookupKey(kid); ok {
+		return k, nil
+	}
+	if time.Since(p.keysAt) < minKeyRefresh {
+		if p.keysError != nil {
+			return nil, p.keysError
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	var set jwks
+	p.keysAt = time.Now()
+	if p.keysError = p.getJSON(ctx, meta.JWKSURI, &set); p.keysError != nil {
+		return nil, p.keysError
+	}
+	p.keys = set.publicKeys()
+	if k, ok := p.lookupKey(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (p *Provider) lookupKey(kid string) (any, bool) {
+	if kid != "" {
+		k, ok := p.keys[kid]
+		return k, ok
+	}
+	// Tanpa kid hanya aman bila provider punya satu kunci
+	if len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
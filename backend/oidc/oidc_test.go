@@ -0,0 +1,230 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"backend/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// startMock runs a mock provider and returns it with a relying party
+// configured for it.
+func startMock(t *testing.T) (*oidctest.Provider, *Provider) {
+	t.Helper()
+	mock, err := oidctest.New("", "wellbeing", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(mock.Handler())
+	t.Cleanup(srv.Close)
+	mock.Issuer = srv.URL
+	p := New(Config{Issuer: srv.URL + "/", ClientID: "wellbeing", ClientSecret: "secret", RedirectURL: "http://app.test/sso/callback"})
+	return mock, p
+}
+
+// authorize follows the browser leg of the flow and returns the code.
+func authorize(t *testing.T, p *Provider, state, nonce, verifier string) string {
+	t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, PKCEChallenge(verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	res, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %d", res.StatusCode)
+	}
+	back, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := back.Query().Get("state"); got != state {
+		t.Fatalf("state = %q, want %q", got, state)
+	}
+	return back.Query().Get("code")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	mock, p := startMock(t)
+	mock.Email, mock.Groups = "alice@example.com", []string{"staff", "hr-admins"}
+	ctx := context.Background()
+
+	code := authorize(t, p, "state-1", "nonce-1", "verifier-1")
+	raw, err := p.Exchange(ctx, code, "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := p.Verify(ctx, raw, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Subject != "mock|alice@example.com" || id.Email != "alice@example.com" || !id.EmailVerified || id.Name != "SSO User" {
+		t.Errorf("identity = %+v", id)
+	}
+	if len(id.Groups) != 2 || id.Groups[1] != "hr-admins" {
+		t.Errorf("groups = %v", id.Groups)
+	}
+
+	// Kode hanya bisa ditukar sekali
+	if _, err := p.Exchange(ctx, code, "verifier-1"); err == nil {
+		t.Error("a code was exchanged twice")
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	_, p := startMock(t)
+	code := authorize(t, p, "state", "nonce", "right-verifier")
+	if _, err := p.Exchange(context.Background(), code, "wrong-verifier"); err == nil {
+		t.Error("Exchange accepted a code with the wrong PKCE verifier")
+	}
+}
+
+func TestExchangeRejectsWrongSecret(t *testing.T) {
+	mock, p := startMock(t)
+	code := authorize(t, p, "state", "nonce", "verifier")
+	mock.ClientSecret = "rotated"
+	if _, err := p.Exchange(context.Background(), code, "verifier"); err == nil {
+		t.Error("Exchange succeeded with a wrong client secret")
+	}
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	mock, p := startMock(t)
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   mock.Issuer,
+			"sub":   "user-1",
+			"aud":   "wellbeing",
+			"iat":   now.Unix(),
+			"exp":   now.Add(5 * time.Minute).Unix(),
+			"nonce": "nonce",
+		}
+	}
+	sign := func(claims jwt.MapClaims) string {
+		raw, err := mock.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	with := func(key string, value any) string {
+		claims := valid()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return sign(claims)
+	}
+	hs256 := jwt.NewWithClaims(jwt.SigningMethodHS256, valid())
+	hs256.Header["kid"] = oidctest.KeyID
+	hmacToken, err := hs256.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, valid())
+	noneToken, err := none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := oidctest.New(mock.Issuer, "wellbeing", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := otherKey.Sign(valid())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Verify(context.Background(), sign(valid()), "nonce"); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	tests := []struct {
+		name  string
+		token string
+		nonce string
+	}{
+		{"wrong nonce", sign(valid()), "other-nonce"},
+		{"missing nonce", with("nonce", nil), "nonce"},
+		{"wrong audience", with("aud", "other-client"), "nonce"},
+		{"several audiences without azp", with("aud", []string{"wellbeing", "other-client"}), "nonce"},
+		{"wrong issuer", with("iss", "https://evil.example"), "nonce"},
+		{"expired", with("exp", now.Add(-time.Hour).Unix()), "nonce"},
+		{"no expiry", with("exp", nil), "nonce"},
+		{"missing subject", with("sub", nil), "nonce"},
+		{"HS256 signed with a shared secret", hmacToken, "nonce"},
+		{"alg none", noneToken, "nonce"},
+		{"signed by another key", forged, "nonce"},
+		{"not a JWT", "not-a-token", "nonce"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.Verify(context.Background(), tt.token, tt.nonce); !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("Verify error = %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestVerifyAcceptsAuthorizedParty(t *testing.T) {
+	mock, p := startMock(t)
+	now := time.Now()
+	raw, err := mock.Sign(jwt.MapClaims{
+		"iss": mock.Issuer, "sub": "user-1", "aud": []string{"wellbeing", "other-client"}, "azp": "wellbeing",
+		"iat": now.Unix(), "exp": now.Add(time.Minute).Unix(), "nonce": "nonce",
+		"email_verified": "true", "groups": "staff",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := p.Verify(context.Background(), raw, "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !id.EmailVerified || len(id.Groups) != 1 || id.Groups[0] != "staff" {
+		t.Errorf("identity = %+v", id)
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	mock, p := startMock(t)
+	mock.Issuer = "https://evil.example"
+	if _, err := p.AuthCodeURL(context.Background(), "state", "nonce", "challenge"); err == nil {
+		t.Error("discovery accepted metadata for another issuer")
+	}
+}
+
+func TestRoleFor(t *testing.T) {
+	p := New(Config{RoleMapping: []GroupRole{
+		{Group: "hr-admins", Role: "org_admin"},
+		{Group: "team-leads", Role: "manager"},
+	}})
+	tests := []struct {
+		groups []string
+		want   string
+	}{
+		{[]string{"hr-admins"}, "org_admin"},
+		{[]string{"team-leads", "hr-admins"}, "org_admin"}, // urutan mapping yang menentukan
+		{[]string{"staff", "team-leads"}, "manager"},
+		{[]string{"HR-Admins"}, ""},
+		{[]string{"staff"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := p.RoleFor(tt.groups); got != tt.want {
+			t.Errorf("RoleFor(%v) = %q, want %q", tt.groups, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"backend/models"
+	"backend/tenant"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var defaultWorkingDays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+// absenceJob marks team members absent on working days they did not check
+// in by the cutoff. Each run looks back a few days so a restart or outage
+// catches up. Runs are idempotent: the unique attendance index allows one
+// record per user and day however many replicas run the job, and the
+// generated absence check-in shares the attendance record's id.
+type absenceJob struct {
+	db       *mongo.Database
+	interval time.Duration
+	cutoff   time.Duration // sejak tengah malam waktu lokal
+	lookback int           // hari sebelum hari ini yang ikut diperiksa
+}
+
+// StartAbsenceJob runs the absence job in the background until ctx ends.
+// ABSENCE_JOB_INTERVAL (default 15m, 0 disables), ABSENCE_CUTOFF (default
+// "12:00", overridable per organization) and ABSENCE_LOOKBACK_DAYS
+// (default 3) configure it.
+func StartAbsenceJob(ctx context.Context, db *mongo.Database) error {
+	job := &absenceJob{db: db, interval: 15 * time.Minute, lookback: 3}
+	if v := os.Getenv("ABSENCE_JOB_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		job.interval = d
+	}
+	if job.interval <= 0 {
+		log.Println("Absence job disabled")
+		return nil
+	}
+	clock := os.Getenv("ABSENCE_CUTOFF")
+	if clock == "" {
+		clock = "12:00"
+	}
+	cutoff, err := parseClock(clock)
+	if err != nil {
+		return errors.New("ABSENCE_CUTOFF must be HH:MM")
+	}
+	job.cutoff = cutoff
+	if v := os.Getenv("ABSENCE_LOOKBACK_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return errors.New("ABSENCE_LOOKBACK_DAYS must be a non-negative number")
+		}
+		job.lookback = n
+	}
+	go func() {
+		ticker := time.NewTicker(job.interval)
+		defer ticker.Stop()
+		for {
+			job.run(ctx, time.Now())
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func (j *absenceJob) run(ctx context.Context, now time.Time) {
+	cur, err := j.db.Collection("organizations").Find(ctx, bson.M{})
+	if err != nil {
+		log.Printf("Absence job: %v", err)
+		return
+	}
+	var orgs []models.Organization
+	if err := cur.All(ctx, &orgs); err != nil {
+		log.Printf("Absence job: %v", err)
+		return
+	}
+	for _, org := range orgs {
+		n, err := j.runOrg(ctx, org, now)
+		if err != nil {
+			log.Printf("Absence job for org %s: %v", org.Slug, err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("Absence job: marked %d absences in org %s", n, org.Slug)
+		}
+	}
+}
+
+type absenceCandidate struct {
+	user   primitive.ObjectID
+	date   string
+	cutoff time.Time
+	loc    *time.Location
+}
+
+// runOrg records the absences of one organization and returns how many it
+// added.
+func (j *absenceJob) runOrg(ctx context.Context, org models.Organization, now time.Time) (int, error) {
+	scoped := tenant.New(j.db, org.ID)
+	workingDays := org.Settings.WorkingDays
+	if len(workingDays) == 0 {
+		workingDays = defaultWorkingDays
+	}
+	cutoff := j.cutoff
+	if org.Settings.AbsenceCutoff != "" {
+		if d, err := parseClock(org.Settings.AbsenceCutoff); err == nil {
+			cutoff = d
+		}
+	}
+
+	// Hanya anggota (atau lead) tim yang diharapkan check-in
+	members, err := scoped.Collection("teams").Distinct(ctx, "members", bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	leads, err := scoped.Collection("teams").Distinct(ctx, "lead", bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	ids := bson.A{}
+	for _, v := range append(members, leads...) {
+		if id, ok := v.(primitive.ObjectID); ok && !id.IsZero() {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	cur, err := scoped.Collection("users").Find(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "deactivated": bson.M{"$ne": true}, "serviceAccount": bson.M{"$ne": true}},
+		options.Find().SetProjection(bson.M{"_id": 1, "timezone": 1}))
+	if err != nil {
+		return 0, err
+	}
+	var users []models.User
+	if err := cur.All(ctx, &users); err != nil {
+		return 0, err
+	}
+
+	candidates := absenceCandidates(users, org.Settings.Timezone, workingDays, cutoff, j.lookback, now)
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+
+	dates := map[string]bool{}
+	for _, cand := range candidates {
+		dates[cand.date] = true
+	}
+	dateList := bson.A{}
+	minDate, maxDate := "", ""
+	for d := range dates {
+		dateList = append(dateList, d)
+		if minDate == "" || d < minDate {
+			minDate = d
+		}
+		if d > maxDate {
+			maxDate = d
+		}
+	}
+	holidays, err := scoped.Collection("holidays").Distinct(ctx, "date", bson.M{"date": bson.M{"$in": dateList}})
+	if err != nil {
+		return 0, err
+	}
+	isHoliday := map[string]bool{}
+	for _, d := range holidays {
+		if s, ok := d.(string); ok {
+			isHoliday[s] = true
+		}
+	}
+
+	var recorded []models.Attendance
+	cur, err = scoped.Collection("attendance").Find(ctx, bson.M{"userId": bson.M{"$in": ids}, "date": bson.M{"$in": dateList}},
+		options.Find().SetProjection(bson.M{"userId": 1, "date": 1, "status": 1, "checkinId": 1, "createdAt": 1}))
+	if err != nil {
+		return 0, err
+	}
+	if err := cur.All(ctx, &recorded); err != nil {
+		return 0, err
+	}
+	existing := map[string]models.Attendance{}
+	absentIDs := bson.A{}
+	for _, a := range recorded {
+		existing[a.UserID.Hex()+a.Date] = a
+		if a.Status == models.AttendanceAbsent && a.CheckinID != nil {
+			absentIDs = append(absentIDs, *a.CheckinID)
+		}
+	}
+	hasCheckin := map[primitive.ObjectID]bool{}
+	if len(absentIDs) > 0 {
+		found, err := scoped.Collection("checkins").Distinct(ctx, "_id", bson.M{"_id": bson.M{"$in": absentIDs}})
+		if err != nil {
+			return 0, err
+		}
+		for _, v := range found {
+			if id, ok := v.(primitive.ObjectID); ok {
+				hasCheckin[id] = true
+			}
+		}
+	}
+
+	var leaves []models.LeaveRequest
+	cur, err = scoped.Collection("leave_requests").Find(ctx, bson.M{
+		"userId":    bson.M{"$in": ids},
+		"status":    models.LeaveApproved,
+		"startDate": bson.M{"$lte": maxDate},
+		"endDate":   bson.M{"$gte": minDate},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := cur.All(ctx, &leaves); err != nil {
+		return 0, err
+	}
+	candidates = excuseAbsences(candidates, isHoliday, leaves)
+
+	added := 0
+	for _, cand := range candidates {
+		if a, ok := existing[cand.user.Hex()+cand.date]; ok {
+			if a.Status == models.AttendanceAbsent && a.CheckinID != nil && !hasCheckin[*a.CheckinID] {
+				// Lengkapi check-in absen yang gagal ditulis pada run sebelumnya
+				if err := ensureAbsenceCheckin(ctx, scoped, a, cand.cutoff); err != nil {
+					return added, err
+				}
+			}
+			continue
+		}
+		id := primitive.NewObjectID()
+		now := time.Now()
+		day := models.Attendance{
+			ID:        id,
+			UserID:    cand.user,
+			Date:      cand.date,
+			Timezone:  cand.loc.String(),
+			Status:    models.AttendanceAbsent,
+			CheckinID: &id,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if _, err := scoped.Collection("attendance").InsertOne(ctx, &day); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				continue // replika lain atau check-in terlambat lebih dulu
+			}
+			return added, err
+		}
+		if err := ensureAbsenceCheckin(ctx, scoped, day, cand.cutoff); err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, nil
+}
+
+// absenceCandidates lists the users and days the job should check: working
+// days within the lookback whose cutoff has passed, in each user's own
+// timezone, skipping days that ended before the account existed.
+func absenceCandidates(users []models.User, orgTZ string, workingDays []time.Weekday, cutoff time.Duration, lookback int, now time.Time) []absenceCandidate {
+	var candidates []absenceCandidate
+	for _, u := range users {
+		loc := locationFor(u.Timezone, orgTZ)
+		today, _ := dayBounds(now, loc)
+		for back := 0; back <= lookback; back++ {
+			day := today.AddDate(0, 0, -back)
+			// Jam lokal, bukan durasi sejak tengah malam: hari pergantian DST tidak 24 jam
+			y, m, d := day.Date()
+			dayCutoff := time.Date(y, m, d, int(cutoff/time.Hour), int(cutoff%time.Hour/time.Minute), 0, 0, loc)
+			if dayCutoff.After(now) || !containsWeekday(workingDays, day.Weekday()) {
+				continue
+			}
+			// Akun yang dibuat setelah cutoff tidak dianggap absen hari itu
+			if u.ID.Timestamp().After(dayCutoff) {
+				continue
+			}
+			candidates = append(candidates, absenceCandidate{user: u.ID, date: day.Format(time.DateOnly), cutoff: dayCutoff, loc: loc})
+		}
+	}
+	return candidates
+}
+
+// excuseAbsences drops candidates whose day is a holiday or falls within
+// one of the user's approved leaves.
+func excuseAbsences(candidates []absenceCandidate, holidays map[string]bool, leaves []models.LeaveRequest) []absenceCandidate {
+	var kept []absenceCandidate
+	for _, cand := range candidates {
+		if !holidays[cand.date] && !onLeave(leaves, cand.user, cand.date) {
+			kept = append(kept, cand)
+		}
+	}
+	return kept
+}
+
+func onLeave(leaves []models.LeaveRequest, user primitive.ObjectID, date string) bool {
+	for _, l := range leaves {
+		if l.UserID == user && l.StartDate <= date && date <= l.EndDate {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureAbsenceCheckin writes the check-in with status "absent" that
+// reports count for an absent attendance record. It uses the record's id,
+// so repeating it is harmless.
+func ensureAbsenceCheckin(ctx context.Context, scoped *tenant.DB, day models.Attendance, at time.Time) error {
+	checkin := models.Checkin{
+		ID:        day.ID,
+		UserID:    day.UserID,
+		Type:      models.CheckinTypeIn,
+		CreatedAt: at,
+		Status:    models.CheckinStatusAbsent,
+	}
+	_, err := scoped.Collection("checkins").InsertOne(ctx, &checkin)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+func containsWeekday(days []time.Weekday, d time.Weekday) bool {
+	for _, w := range days {
+		if w == d {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package routes
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func candidateDates(cands []absenceCandidate) []string {
+	var dates []string
+	for _, c := range cands {
+		dates = append(dates, c.date)
+	}
+	return dates
+}
+
+func TestAbsenceCandidates(t *testing.T) {
+	old := primitive.NewObjectIDFromTimestamp(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	newcomer := primitive.NewObjectIDFromTimestamp(time.Date(2024, time.March, 12, 13, 0, 0, 0, time.UTC)) // Selasa, setelah cutoff
+	wed := func(h int) time.Time { return time.Date(2024, time.March, 13, h, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name  string
+		user  models.User
+		orgTZ string
+		now   time.Time
+		want  []string
+	}{
+		{"working days in lookback", models.User{ID: old}, "", wed(13), []string{"2024-03-13", "2024-03-12", "2024-03-11"}},
+		{"today before cutoff", models.User{ID: old}, "", wed(11), []string{"2024-03-12", "2024-03-11"}},
+		{"account created after cutoff", models.User{ID: newcomer}, "", wed(13), []string{"2024-03-13"}},
+		{"user timezone", models.User{ID: old, Timezone: "Asia/Jakarta"}, "", wed(6), []string{"2024-03-13", "2024-03-12", "2024-03-11"}},
+		{"org timezone", models.User{ID: old}, "Asia/Jakarta", wed(6), []string{"2024-03-13", "2024-03-12", "2024-03-11"}},
+		{"utc before cutoff", models.User{ID: old}, "", wed(6), []string{"2024-03-12", "2024-03-11"}},
+	}
+	for _, tt := range tests {
+		got := candidateDates(absenceCandidates([]models.User{tt.user}, tt.orgTZ, defaultWorkingDays, 12*time.Hour, 3, tt.now))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: dates = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAbsenceCandidatesDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	// 10 Maret 2024: jam maju pukul 02:00, hari itu hanya 23 jam
+	user := models.User{ID: primitive.NewObjectIDFromTimestamp(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)), Timezone: "America/New_York"}
+	now := time.Date(2024, time.March, 10, 23, 0, 0, 0, ny)
+	everyDay := []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+	got := absenceCandidates([]models.User{user}, "", everyDay, 12*time.Hour+30*time.Minute, 0, now)
+	if len(got) != 1 {
+		t.Fatalf("candidates = %v, want one", got)
+	}
+	if want := time.Date(2024, time.March, 10, 12, 30, 0, 0, ny); !got[0].cutoff.Equal(want) {
+		t.Errorf("cutoff = %v, want %v", got[0].cutoff, want)
+	}
+}
+
+func TestExcuseAbsences(t *testing.T) {
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+	candidates := []absenceCandidate{
+		{user: alice, date: "2024-03-11"},
+		{user: alice, date: "2024-03-12"},
+		{user: alice, date: "2024-03-13"},
+		{user: bob, date: "2024-03-12"},
+		{user: bob, date: "2024-03-13"},
+	}
+	holidays := map[string]bool{"2024-03-11": true}
+	leaves := []models.LeaveRequest{
+		{UserID: alice, StartDate: "2024-03-12", EndDate: "2024-03-13"},
+		{UserID: bob, StartDate: "2024-03-14", EndDate: "2024-03-15"},
+	}
+	got := excuseAbsences(candidates, holidays, leaves)
+	want := []absenceCandidate{{user: bob, date: "2024-03-12"}, {user: bob, date: "2024-03-13"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("excuseAbsences = %v, want %v", got, want)
+	}
+}
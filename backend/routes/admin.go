@@ -0,0 +1,291 @@
+package routes
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"backend/audit"
+	"backend/auth"
+	"backend/mailer"
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageParams reads the 1-based ?page and ?limit query parameters.
+func pageParams(c *fiber.Ctx) (page, limit int64) {
+	page = int64(c.QueryInt("page", 1))
+	if page < 1 {
+		page = 1
+	}
+	limit = int64(c.QueryInt("limit", defaultPageSize))
+	if limit < 1 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	return page, limit
+}
+
+// adminUserView is what user management screens see about an account.
+func adminUserView(u models.User) fiber.Map {
+	view := userProfile(u)
+	view["serviceAccount"] = u.ServiceAccount
+	view["totpEnabled"] = u.TOTPEnabled
+	view["deactivated"] = u.Deactivated
+	view["deactivatedAt"] = u.DeactivatedAt
+	view["passwordResetRequired"] = u.PasswordResetRequired
+	return view
+}
+
+func accountDeactivated(c *fiber.Ctx) error {
+	return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Account has been deactivated", "code": "account_deactivated"})
+}
+
+func RegisterAdminRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager, mail mailer.Mailer) {
+	authRequired := authn.Required()
+	canManage := auth.Authorize(auth.Has(auth.PermUsersManage))
+	trail := audit.New(db)
+
+	// targetUser loads the :id user in the caller's organization. Admins
+	// may not act on themselves or on anyone holding a role they could not
+	// grant. When it returns nil the error response has been written.
+	targetUser := func(c *fiber.Ctx) (*models.User, error) {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return nil, c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user id"})
+		}
+		p := auth.PrincipalFrom(c)
+		if id == p.UserID {
+			return nil, c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "You cannot change your own account here"})
+		}
+		var user models.User
+		if err := orgDB(c, db).Collection("users").FindOne(context.Background(), bson.M{"_id": id}).Decode(&user); err != nil {
+			return nil, c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		}
+		if !p.CanGrant(user.Role) {
+			return nil, auth.Forbidden(c)
+		}
+		return &user, nil
+	}
+
+	record := func(c *fiber.Ctx, action string, target models.User, details map[string]any) {
+		p := auth.PrincipalFrom(c)
+		trail.Record(context.Background(), models.AuditEntry{
+			OrgID:    target.OrgID,
+			Action:   action,
+			ActorID:  &p.UserID,
+			TargetID: &target.ID,
+			IP:       c.IP(),
+			Details:  details,
+		})
+	}
+
+	// GET /api/admin/users?q=&role=&status=active|deactivated&page=&limit=
+	app.Get("/api/admin/users", authRequired, canManage, func(c *fiber.Ctx) error {
+		filter := bson.M{}
+		if q := strings.TrimSpace(c.Query("q")); q != "" {
+			pattern := primitive.Regex{Pattern: regexp.QuoteMeta(q), Options: "i"}
+			filter["$or"] = bson.A{bson.M{"name": pattern}, bson.M{"email": pattern}}
+		}
+		if role := c.Query("role"); role != "" {
+			if !auth.ValidRole(role) {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid role"})
+			}
+			filter["role"] = role
+		}
+		switch c.Query("status") {
+		case "":
+		case "active":
+			filter["deactivated"] = bson.M{"$ne": true}
+		case "deactivated":
+			filter["deactivated"] = true
+		default:
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Status must be active or deactivated"})
+		}
+		page, limit := pageParams(c)
+		ctx := context.Background()
+		users := orgDB(c, db).Collection("users")
+		total, err := users.CountDocuments(ctx, filter)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		opts := options.Find().
+			SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}).
+			SetSkip((page - 1) * limit).
+			SetLimit(limit)
+		cur, err := users.Find(ctx, filter, opts)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		var found []models.User
+		if err := cur.All(ctx, &found); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		data := make([]fiber.Map, 0, len(found))
+		for _, u := range found {
+			data = append(data, adminUserView(u))
+		}
+		return c.JSON(fiber.Map{"data": data, "total": total, "page": page, "limit": limit})
+	})
+
+	// PUT /api/users/:id/role - ganti role; token lama memakai role baru setelah refresh
+	app.Put("/api/users/:id/role", authRequired, canManage, func(c *fiber.Ctx) error {
+		var req struct {
+			Role string `json:"role"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !auth.ValidRole(req.Role) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid role"})
+		}
+		if !auth.PrincipalFrom(c).CanGrant(req.Role) {
+			return auth.Forbidden(c)
+		}
+		user, err := targetUser(c)
+		if user == nil {
+			return err
+		}
+		if user.Role == req.Role {
+			return c.JSON(adminUserView(*user))
+		}
+		_, err = orgDB(c, db).Collection("users").UpdateOne(context.Background(), bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"role": req.Role}})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		record(c, audit.ActionUserRoleChanged, *user, map[string]any{"from": user.Role, "to": req.Role})
+		user.Role = req.Role
+		return c.JSON(adminUserView(*user))
+	})
+
+	// POST /api/users/:id/deactivate - blokir login, cabut semua sesi
+	app.Post("/api/users/:id/deactivate", authRequired, canManage, func(c *fiber.Ctx) error {
+		user, err := targetUser(c)
+		if user == nil {
+			return err
+		}
+		if user.Deactivated {
+			return c.JSON(adminUserView(*user))
+		}
+		ctx := context.Background()
+		now := time.Now()
+		_, err = orgDB(c, db).Collection("users").UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"deactivated": true, "deactivatedAt": now}})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		revoked, err := authn.RevokeAllSessions(ctx, user.ID, primitive.NilObjectID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		record(c, audit.ActionUserDeactivated, *user, map[string]any{"sessionsRevoked": revoked})
+		user.Deactivated, user.DeactivatedAt = true, &now
+		return c.JSON(adminUserView(*user))
+	})
+
+	app.Post("/api/users/:id/reactivate", authRequired, canManage, func(c *fiber.Ctx) error {
+		user, err := targetUser(c)
+		if user == nil {
+			return err
+		}
+		if !user.Deactivated {
+			return c.JSON(adminUserView(*user))
+		}
+		_, err = orgDB(c, db).Collection("users").UpdateOne(context.Background(), bson.M{"_id": user.ID}, bson.M{"$unset": bson.M{"deactivated": "", "deactivatedAt": ""}})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		record(c, audit.ActionUserReactivated, *user, nil)
+		user.Deactivated, user.DeactivatedAt = false, nil
+		return c.JSON(adminUserView(*user))
+	})
+
+	// POST /api/users/:id/force-password-reset - cabut sesi, kirim link reset
+	app.Post("/api/users/:id/force-password-reset", authRequired, canManage, func(c *fiber.Ctx) error {
+		user, err := targetUser(c)
+		if user == nil {
+			return err
+		}
+		if user.ServiceAccount {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Service accounts have no password"})
+		}
+		ctx := context.Background()
+		_, err = orgDB(c, db).Collection("users").UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"passwordResetRequired": true}})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		revoked, err := authn.RevokeAllSessions(ctx, user.ID, primitive.NilObjectID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		emailSent := true
+		if err := sendPasswordReset(ctx, authn, mail, *user); err != nil {
+			log.Printf("Password reset for %s: %v", user.ID.Hex(), err)
+			emailSent = false
+		}
+		record(c, audit.ActionPasswordResetForced, *user, map[string]any{"sessionsRevoked": revoked, "emailSent": emailSent})
+		user.PasswordResetRequired = true
+		return c.JSON(fiber.Map{"user": adminUserView(*user), "emailSent": emailSent})
+	})
+
+	// POST /api/users/:id/unlock - buka kunci akun setelah terlalu banyak login gagal
+	app.Post("/api/users/:id/unlock", authRequired, auth.Authorize(auth.Has(auth.PermUsersUnlock)), func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user id"})
+		}
+		ctx := context.Background()
+		var user models.User
+		if err := orgDB(c, db).Collection("users").FindOne(ctx, bson.M{"_id": id}).Decode(&user); err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		}
+		wasLocked, err := authn.UnlockAccount(ctx, user, auth.PrincipalFrom(c), c.IP())
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"success": true, "wasLocked": wasLocked})
+	})
+
+	// GET /api/admin/audit?action=&targetId=&page=&limit= - jejak audit organisasi
+	app.Get("/api/admin/audit", authRequired, auth.Authorize(auth.Has(auth.PermAuditRead)), func(c *fiber.Ctx) error {
+		filter := bson.M{"orgId": auth.PrincipalFrom(c).OrgID}
+		if action := c.Query("action"); action != "" {
+			filter["action"] = action
+		}
+		if target := c.Query("targetId"); target != "" {
+			id, err := primitive.ObjectIDFromHex(target)
+			if err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid targetId"})
+			}
+			filter["targetId"] = id
+		}
+		page, limit := pageParams(c)
+		ctx := context.Background()
+		col := db.Collection("audit_log")
+		total, err := col.CountDocuments(ctx, filter)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetSkip((page - 1) * limit).SetLimit(limit)
+		cur, err := col.Find(ctx, filter, opts)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		entries := []models.AuditEntry{}
+		if err := cur.All(ctx, &entries); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"data": entries, "total": total, "page": page, "limit": limit})
+	})
+}
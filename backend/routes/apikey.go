@@ -0,0 +1,205 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/auth"
+	"backend/models"
+	"backend/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const maxAPIKeyLifetimeDays = 365
+
+type apiKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expiresInDays"` // 0 = tidak kedaluwarsa
+}
+
+// createAPIKey validates req and issues a key for owner. The plaintext key
+// is only ever part of this response.
+func createAPIKey(c *fiber.Ctx, authn *auth.Manager, owner models.User, req apiKeyRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxNameLength {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Name must be between 1 and 100 characters"})
+	}
+	scopes, err := auth.ValidateScopes(req.Scopes)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Scopes must be <resource>:read or <resource>:write", "resources": auth.APIKeyResources})
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxAPIKeyLifetimeDays {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "expiresInDays must be between 0 and 365"})
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		t := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &t
+	}
+	key, apiKey, err := authn.CreateAPIKey(context.Background(), owner, name, scopes, expiresAt, auth.PrincipalFrom(c).UserID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(http.StatusCreated).JSON(fiber.Map{"key": key, "apiKey": apiKey})
+}
+
+func RegisterAPIKeyRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager) {
+	authRequired := authn.Required()
+	canManageServiceAccounts := auth.Authorize(auth.Has(auth.PermServiceAccounts))
+
+	app.Get("/api/api-keys", authRequired, func(c *fiber.Ctx) error {
+		keys, err := authn.ListAPIKeys(context.Background(), auth.PrincipalFrom(c).UserID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(keys)
+	})
+
+	app.Post("/api/api-keys", authRequired, func(c *fiber.Ctx) error {
+		var req apiKeyRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		var owner models.User
+		if err := orgDB(c, db).Collection("users").FindOne(context.Background(), bson.M{"_id": auth.PrincipalFrom(c).UserID}).Decode(&owner); err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		}
+		return createAPIKey(c, authn, owner, req)
+	})
+
+	app.Delete("/api/api-keys/:id", authRequired, func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid API key id"})
+		}
+		revoked, err := authn.RevokeAPIKey(context.Background(), auth.PrincipalFrom(c).UserID, id)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !revoked {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API key not found"})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	})
+
+	// Service account: user tanpa password khusus integrasi, dikelola admin
+	app.Post("/api/service-accounts", authRequired, canManageServiceAccounts, func(c *fiber.Ctx) error {
+		var req struct {
+			Name string `json:"name"`
+			Role string `json:"role"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" || len(name) > maxNameLength {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Name must be between 1 and 100 characters"})
+		}
+		if req.Role == "" {
+			req.Role = auth.RoleMember
+		}
+		p := auth.PrincipalFrom(c)
+		if !auth.ValidRole(req.Role) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid role"})
+		}
+		if !p.CanGrant(req.Role) {
+			return auth.Forbidden(c)
+		}
+		now := time.Now()
+		id := primitive.NewObjectID()
+		user := models.User{
+			ID:              id,
+			Name:            name,
+			Email:           "svc-" + id.Hex() + "@service-account.invalid",
+			Role:            req.Role,
+			ServiceAccount:  true,
+			EmailVerified:   true,
+			EmailVerifiedAt: &now,
+		}
+		if _, err := orgDB(c, db).Collection("users").InsertOne(context.Background(), &user); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(http.StatusCreated).JSON(user)
+	})
+
+	app.Get("/api/service-accounts", authRequired, canManageServiceAccounts, func(c *fiber.Ctx) error {
+		ctx := context.Background()
+		cur, err := orgDB(c, db).Collection("users").Find(ctx, bson.M{"serviceAccount": true})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		users := []models.User{}
+		if err := cur.All(ctx, &users); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(users)
+	})
+
+	serviceAccount := func(c *fiber.Ctx) (*models.User, error) {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return nil, mongo.ErrNoDocuments
+		}
+		var user models.User
+		err = orgDB(c, db).Collection("users").FindOne(context.Background(), bson.M{"_id": id, "serviceAccount": true}).Decode(&user)
+		return &user, err
+	}
+
+	app.Get("/api/service-accounts/:id/keys", authRequired, canManageServiceAccounts, func(c *fiber.Ctx) error {
+		user, err := serviceAccount(c)
+		if err != nil {
+			return serviceAccountError(c, err)
+		}
+		keys, err := authn.ListAPIKeys(context.Background(), user.ID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(keys)
+	})
+
+	app.Post("/api/service-accounts/:id/keys", authRequired, canManageServiceAccounts, func(c *fiber.Ctx) error {
+		var req apiKeyRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		user, err := serviceAccount(c)
+		if err != nil {
+			return serviceAccountError(c, err)
+		}
+		return createAPIKey(c, authn, *user, req)
+	})
+
+	app.Delete("/api/service-accounts/:id/keys/:keyId", authRequired, canManageServiceAccounts, func(c *fiber.Ctx) error {
+		user, err := serviceAccount(c)
+		if err != nil {
+			return serviceAccountError(c, err)
+		}
+		keyID, err := primitive.ObjectIDFromHex(c.Params("keyId"))
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid API key id"})
+		}
+		revoked, err := authn.RevokeAPIKey(context.Background(), user.ID, keyID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !revoked {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "API key not found"})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	})
+}
+
+func serviceAccountError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, tenant.ErrNoOrg) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Service account not found"})
+	}
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+}
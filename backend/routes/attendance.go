@@ -0,0 +1,263 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"backend/auth"
+	"backend/models"
+	"backend/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxShiftLength is how long after checking in a checkout still closes that
+// day, so night shifts can check out after midnight.
+const maxShiftLength = 24 * time.Hour
+
+var (
+	errAlreadyCheckedIn  = errors.New("Already checked in today")
+	errAlreadyCheckedOut = errors.New("Already checked out")
+	errNotCheckedIn      = errors.New("Not checked in")
+)
+
+// EnsureAttendanceIndexes enforces one attendance record per user and day
+// and one holiday per date, and backs the absence job's lookups.
+func EnsureAttendanceIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("attendance").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: tenant.Field, Value: 1}, {Key: "userId", Value: 1}, {Key: "date", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: tenant.Field, Value: 1}, {Key: "date", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = db.Collection("holidays").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: tenant.Field, Value: 1}, {Key: "date", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = db.Collection("leave_requests").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: tenant.Field, Value: 1}, {Key: "userId", Value: 1}, {Key: "startDate", Value: 1}},
+	})
+	return err
+}
+
+// startAttendance opens the user's attendance record for the day of at.
+// The unique index turns a second check-in on the same day into
+// errAlreadyCheckedIn, even when two requests race. A late check-in after
+// the day was marked absent replaces the absence.
+func startAttendance(ctx context.Context, scoped *tenant.DB, userID primitive.ObjectID, loc *time.Location, at time.Time, checkinID primitive.ObjectID) (*models.Attendance, error) {
+	day := models.Attendance{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Date:      at.In(loc).Format(time.DateOnly),
+		Timezone:  loc.String(),
+		Status:    models.AttendanceOpen,
+		CheckinID: &checkinID,
+		CheckinAt: &at,
+		CreatedAt: at,
+		UpdatedAt: at,
+	}
+	_, err := scoped.Collection("attendance").InsertOne(ctx, &day)
+	if mongo.IsDuplicateKeyError(err) {
+		return replaceAbsence(ctx, scoped, day)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &day, nil
+}
+
+// replaceAbsence turns the absent record for day.Date into day and removes
+// the generated absence check-in.
+func replaceAbsence(ctx context.Context, scoped *tenant.DB, day models.Attendance) (*models.Attendance, error) {
+	var absent models.Attendance
+	err := scoped.Collection("attendance").FindOneAndUpdate(ctx,
+		bson.M{"userId": day.UserID, "date": day.Date, "status": models.AttendanceAbsent},
+		bson.M{"$set": bson.M{
+			"status":    day.Status,
+			"checkinId": day.CheckinID,
+			"checkinAt": day.CheckinAt,
+			"updatedAt": day.UpdatedAt,
+		}},
+	).Decode(&absent)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, errAlreadyCheckedIn
+	}
+	if err != nil {
+		return nil, err
+	}
+	if absent.CheckinID != nil {
+		if _, err := scoped.Collection("checkins").DeleteOne(ctx, bson.M{"_id": *absent.CheckinID, "status": models.CheckinStatusAbsent}); err != nil {
+			return nil, err
+		}
+	}
+	day.ID, day.CreatedAt = absent.ID, absent.CreatedAt
+	return &day, nil
+}
+
+// finishAttendance closes the user's most recent attendance record that was
+// opened within maxShiftLength of at and records the worked duration.
+func finishAttendance(ctx context.Context, scoped *tenant.DB, userID primitive.ObjectID, at time.Time, checkoutID primitive.ObjectID) (*models.Attendance, error) {
+	col := scoped.Collection("attendance")
+	var day models.Attendance
+	err := col.FindOne(ctx,
+		bson.M{"userId": userID, "checkinAt": bson.M{"$gte": at.Add(-maxShiftLength), "$lte": at}},
+		options.FindOne().SetSort(bson.D{{Key: "checkinAt", Value: -1}}),
+	).Decode(&day)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, errNotCheckedIn
+	}
+	if err != nil {
+		return nil, err
+	}
+	if day.Status != models.AttendanceOpen {
+		return nil, errAlreadyCheckedOut
+	}
+	worked := int64(at.Sub(*day.CheckinAt) / time.Second)
+	res, err := col.UpdateOne(ctx,
+		bson.M{"_id": day.ID, "status": models.AttendanceOpen},
+		bson.M{"$set": bson.M{
+			"status":        models.AttendanceComplete,
+			"checkoutId":    checkoutID,
+			"checkoutAt":    at,
+			"workedSeconds": worked,
+			"updatedAt":     at,
+		}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if res.ModifiedCount == 0 {
+		return nil, errAlreadyCheckedOut
+	}
+	day.Status, day.CheckoutID, day.CheckoutAt, day.WorkedSeconds = models.AttendanceComplete, &checkoutID, &at, worked
+	return &day, nil
+}
+
+// undoAttendance reverts startAttendance or finishAttendance when the
+// check-in document itself could not be stored.
+func undoAttendance(ctx context.Context, scoped *tenant.DB, day *models.Attendance, checkinType string) error {
+	col := scoped.Collection("attendance")
+	if checkinType == models.CheckinTypeIn {
+		_, err := col.DeleteOne(ctx, bson.M{"_id": day.ID, "status": models.AttendanceOpen})
+		return err
+	}
+	_, err := col.UpdateOne(ctx, bson.M{"_id": day.ID},
+		bson.M{
+			"$set":   bson.M{"status": models.AttendanceOpen, "workedSeconds": 0},
+			"$unset": bson.M{"checkoutId": "", "checkoutAt": ""},
+		})
+	return err
+}
+
+// periodRange returns the first date of the day, ISO week (from Monday) or
+// month containing date, and the first date after it.
+func periodRange(period string, date time.Time) (time.Time, time.Time, bool) {
+	y, m, d := date.Date()
+	switch period {
+	case "day":
+		start := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 0, 1), true
+	case "week":
+		offset := (int(date.Weekday()) + 6) % 7
+		start := time.Date(y, m, d-offset, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 0, 7), true
+	case "month":
+		start := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0), true
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+func RegisterAttendanceRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager) {
+	authRequired := authn.Required()
+
+	// GET /api/timesheet/:period?date=YYYY-MM-DD&userId= - period: day, week atau month
+	app.Get("/api/timesheet/:period", authRequired, func(c *fiber.Ctx) error {
+		p := auth.PrincipalFrom(c)
+		ctx := context.Background()
+		scoped := orgDB(c, db)
+		userID := p.UserID
+		if v := c.Query("userId"); v != "" {
+			id, err := primitive.ObjectIDFromHex(v)
+			if err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid userId"})
+			}
+			if id != p.UserID && !p.Can(auth.PermCheckinsReadAll) {
+				// Lead tim boleh melihat timesheet anggotanya
+				leads, err := scoped.Collection("teams").CountDocuments(ctx, bson.M{"lead": p.UserID, "members": id})
+				if err != nil {
+					return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+				}
+				if leads == 0 {
+					return auth.Forbidden(c)
+				}
+			}
+			userID = id
+		}
+		loc, err := userLocation(ctx, db, userID, p.OrgID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		date := time.Now().In(loc)
+		if v := c.Query("date"); v != "" {
+			if date, err = time.Parse(time.DateOnly, v); err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Date must be YYYY-MM-DD"})
+			}
+		}
+		period := c.Params("period")
+		start, end, ok := periodRange(period, date)
+		if !ok {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Period must be day, week or month"})
+		}
+		from, until := start.Format(time.DateOnly), end.Format(time.DateOnly)
+		cur, err := scoped.Collection("attendance").Find(ctx,
+			bson.M{"userId": userID, "date": bson.M{"$gte": from, "$lt": until}},
+			options.Find().SetSort(bson.D{{Key: "date", Value: 1}}))
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		days := []models.Attendance{}
+		if err := cur.All(ctx, &days); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		var total int64
+		present, open, absent := 0, 0, 0
+		for _, d := range days {
+			total += d.WorkedSeconds
+			switch d.Status {
+			case models.AttendanceOpen:
+				present++
+				open++
+			case models.AttendanceComplete:
+				present++
+			case models.AttendanceAbsent:
+				absent++
+			}
+		}
+		return c.JSON(fiber.Map{
+			"userId":             userID,
+			"period":             period,
+			"from":               from,
+			"to":                 end.AddDate(0, 0, -1).Format(time.DateOnly),
+			"timezone":           loc.String(),
+			"days":               days,
+			"totalWorkedSeconds": total,
+			"daysPresent":        present,
+			"openDays":           open,
+			"daysAbsent":         absent,
+		})
+	})
+}
@@ -0,0 +1,53 @@
+package routes
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodRange(t *testing.T) {
+	date := time.Date(2024, time.February, 29, 15, 30, 0, 0, time.UTC) // Kamis
+	tests := []struct {
+		period     string
+		start, end string
+		ok         bool
+	}{
+		{"day", "2024-02-29", "2024-03-01", true},
+		{"week", "2024-02-26", "2024-03-04", true},
+		{"month", "2024-02-01", "2024-03-01", true},
+		{"year", "", "", false},
+	}
+	for _, tt := range tests {
+		start, end, ok := periodRange(tt.period, date)
+		if ok != tt.ok {
+			t.Errorf("periodRange(%q) ok = %v, want %v", tt.period, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if got := start.Format(time.DateOnly); got != tt.start {
+			t.Errorf("periodRange(%q) start = %s, want %s", tt.period, got, tt.start)
+		}
+		if got := end.Format(time.DateOnly); got != tt.end {
+			t.Errorf("periodRange(%q) end = %s, want %s", tt.period, got, tt.end)
+		}
+	}
+}
+
+func TestPeriodRangeWeekStartsMonday(t *testing.T) {
+	for _, tt := range []struct{ date, start string }{
+		{"2024-03-03", "2024-02-26"}, // Minggu masih minggu yang sama
+		{"2024-03-04", "2024-03-04"}, // Senin
+		{"2024-12-31", "2024-12-30"}, // lintas tahun
+	} {
+		date, _ := time.Parse(time.DateOnly, tt.date)
+		start, end, _ := periodRange("week", date)
+		if got := start.Format(time.DateOnly); got != tt.start {
+			t.Errorf("week of %s starts %s, want %s", tt.date, got, tt.start)
+		}
+		if end.Sub(start) != 7*24*time.Hour {
+			t.Errorf("week of %s lasts %s", tt.date, end.Sub(start))
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package routes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	avatarURLPrefix = "/uploads/avatars"
+	maxAvatarSize   = 2 << 20
+)
+
+var errInvalidAvatar = errors.New("invalid avatar image")
+
+// defaultAvatarDir is where uploaded avatars are written unless AVATAR_DIR
+// names another directory.
+var defaultAvatarDir = filepath.Join("uploads", "avatars")
+
+var avatarExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// saveAvatar validates the uploaded image by sniffing its content and stores
+// it under dir, returning the URL it is served from.
+func saveAvatar(dir string, fh *multipart.FileHeader, userID primitive.ObjectID) (string, error) {
+	if fh.Size > maxAvatarSize {
+		return "", errInvalidAvatar
+	}
+	src, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, maxAvatarSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxAvatarSize {
+		return "", errInvalidAvatar
+	}
+	ext, ok := avatarExtensions[http.DetectContentType(data)]
+	if !ok {
+		return "", errInvalidAvatar
+	}
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	name := userID.Hex() + "-" + hex.EncodeToString(suffix) + ext
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+		return "", err
+	}
+	return avatarURLPrefix + "/" + name, nil
+}
+
+// removeAvatar deletes a previously uploaded avatar from dir. External URLs
+// are left alone.
+func removeAvatar(dir, avatarURL string) {
+	name, ok := strings.CutPrefix(avatarURL, avatarURLPrefix+"/")
+	if !ok || name == "" || strings.ContainsAny(name, `/\`) {
+		return
+	}
+	_ = os.Remove(filepath.Join(dir, name))
+}
@@ -2,121 +2,385 @@ package routes
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"log"
+	"math"
 	"net/http"
-	"os"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"backend/auth"
 	"backend/models"
+	"backend/storage"
+	"backend/tenant"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func RegisterCheckinRoutes(app *fiber.App, db *mongo.Database) {
-	jwtSecret := []byte(os.Getenv("JWT_SECRET")) // Pindahkan ke dalam fungsi agar selalu update
-	authRequired := func(c *fiber.Ctx) error {
-		tokenStr := c.Get("Authorization")
-		if tokenStr == "" || len(tokenStr) < 8 {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Missing or invalid token"})
+const maxDescriptionLength = 500
+
+// checkinRequest is the body of POST /api/checkins.
+type checkinRequest struct {
+	Type        string             `json:"type"`
+	Mood        string             `json:"mood"`
+	Description string             `json:"description"`
+	SelfieID    string             `json:"selfieId"`    // dari POST /api/upload
+	SelfieImage string             `json:"selfieImage"` // data URL, disimpan ke blob store
+	FaceData    *models.FaceResult `json:"faceData"`
+	// Dengan skala mood organisasi: nilai opsi dan jawaban per key pertanyaan
+	MoodValue *int           `json:"moodValue"`
+	Answers   map[string]any `json:"answers"`
+
+	moodScore  *int
+	moodAnswer *models.MoodAnswer
+}
+
+// validate checks the request and normalizes it in place: the mood becomes
+// its canonical name, taken from the face expression when no mood was
+// given. With an active mood scale the answer is picked by moodValue, or
+// by a mood naming one of the scale's options, and the extra questions are
+// checked against the scale. Checkouts need a mood; check-ins may go
+// without.
+func (r *checkinRequest) validate(scale *models.MoodScale) []fieldError {
+	var errs []fieldError
+	if r.Type != models.CheckinTypeIn && r.Type != models.CheckinTypeOut {
+		errs = append(errs, fieldError{"type", "must be checkin or checkout"})
+	}
+	if f := r.FaceData; f != nil {
+		f.Gender = strings.ToLower(strings.TrimSpace(f.Gender))
+		f.Expression = strings.ToLower(strings.TrimSpace(f.Expression))
+		if math.IsNaN(f.Age) || f.Age < 0 || f.Age > 120 {
+			errs = append(errs, fieldError{"faceData.age", "must be between 0 and 120"})
 		}
-		tokenStr = tokenStr[7:] // Bearer ...
-		token, err := jwt.ParseWithClaims(tokenStr, jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
-		if err != nil || !token.Valid {
-			// Print pesan error JWT parse yang jelas
-			fmt.Println("JWT parse error:", err)
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+		if f.Gender != "" && f.Gender != "male" && f.Gender != "female" {
+			errs = append(errs, fieldError{"faceData.gender", "must be male or female"})
+		}
+		if f.Expression != "" && !faceExpressions[f.Expression] {
+			errs = append(errs, fieldError{"faceData.expression", "is not a face-api expression"})
+		}
+	}
+	if scale != nil {
+		errs = append(errs, r.answerScale(scale)...)
+	} else if r.MoodValue != nil || len(r.Answers) > 0 {
+		errs = append(errs, fieldError{"moodValue", "the organization has no mood scale"})
+	} else {
+		errs = append(errs, r.canonicalMood()...)
+	}
+	return append(errs, r.checkDescription()...)
+}
+
+func (r *checkinRequest) checkDescription() []fieldError {
+	r.Description = strings.TrimSpace(r.Description)
+	if utf8.RuneCountInString(r.Description) > maxDescriptionLength {
+		return []fieldError{{"description", "must be at most 500 characters"}}
+	}
+	return nil
+}
+
+func (r *checkinRequest) canonicalMood() []fieldError {
+	given := r.Mood
+	if moodMissing(given) && r.FaceData != nil {
+		given = r.FaceData.Expression
+	}
+	switch m, ok := normalizeMood(given); {
+	case ok:
+		r.Mood = m.Name
+		r.moodScore = &m.Valence
+	case !moodMissing(given):
+		return []fieldError{{"mood", "must be one of Happy, Neutral, Stressed"}}
+	case r.Type == models.CheckinTypeOut:
+		return []fieldError{{"mood", "is required for a checkout"}}
+	default:
+		r.Mood = ""
+	}
+	return nil
+}
+
+func (r *checkinRequest) answerScale(scale *models.MoodScale) []fieldError {
+	var errs []fieldError
+	var option *models.MoodOption
+	switch {
+	case r.MoodValue != nil:
+		if option = findMoodOption(scale.Options, *r.MoodValue); option == nil {
+			errs = append(errs, fieldError{"moodValue", "is not an option of the active mood scale"})
+		}
+	case !moodMissing(r.Mood):
+		if option = moodOptionFor(scale, r.Mood); option == nil {
+			errs = append(errs, fieldError{"mood", "is not an option of the active mood scale"})
+		}
+	}
+	if option == nil && len(errs) == 0 {
+		if r.Type == models.CheckinTypeOut {
+			errs = append(errs, fieldError{"moodValue", "is required for a checkout"})
+		}
+		// Check-in tanpa jawaban: pakai ekspresi wajah bila ada
+		r.Mood = ""
+		if r.FaceData != nil {
+			if m, ok := normalizeMood(r.FaceData.Expression); ok {
+				r.Mood = m.Name
+				r.moodScore = &m.Valence
+			}
+		}
+	}
+	answer, answerErrs := answerMoodScale(scale, option, r.Answers)
+	errs = append(errs, answerErrs...)
+	if option != nil {
+		r.moodAnswer = answer
+		r.Mood = option.Mood
+		r.moodScore = nil
+		if m, ok := normalizeMood(option.Mood); ok {
+			r.moodScore = &m.Valence
+		}
+	}
+	return errs
+}
+
+var (
+	errSelfieUnavailable = errors.New("selfieId does not name an unused upload of yours")
+	errSelfieStore       = errors.New("Failed to store image")
+	errDuplicateClientID = errors.New("A check-in with this clientId already exists")
+)
+
+// createCheckin stores a validated check-in of userID made at at, together
+// with its selfie and attendance record. Attendance goes first so a check-in
+// breaking checkin -> checkout ordering is never stored, and neither is its
+// selfie. The returned check-in is not signed.
+func createCheckin(ctx context.Context, db *mongo.Database, store storage.BlobStore, scoped *tenant.DB, userID primitive.ObjectID, req *checkinRequest, at time.Time, clientID string) (*models.Checkin, error) {
+	loc, err := userLocation(ctx, db, userID, scoped.OrgID())
+	if err != nil {
+		return nil, err
+	}
+	var selfie *models.Upload
+	if req.SelfieID != "" {
+		id, err := primitive.ObjectIDFromHex(req.SelfieID)
+		if err == nil {
+			selfie, err = unusedSelfie(ctx, scoped, id, userID)
+		}
+		if err != nil {
+			return nil, errSelfieUnavailable
+		}
+	}
+	checkin := models.Checkin{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		Type:        req.Type,
+		Mood:        req.Mood,
+		MoodScore:   req.moodScore,
+		MoodAnswer:  req.moodAnswer,
+		Description: req.Description,
+		CreatedAt:   at,
+		FaceResult:  req.FaceData,
+		Status:      models.CheckinStatusPresent,
+		ClientID:    clientID,
+	}
+	if clientID != "" {
+		now := time.Now()
+		checkin.ReceivedAt = &now
+	}
+	// Catat kehadiran dulu: checkin -> checkout, satu kali per hari. Selfie
+	// baru disimpan setelahnya agar check-in yang ditolak tidak
+	// meninggalkan blob
+	var day *models.Attendance
+	if req.Type == models.CheckinTypeIn {
+		day, err = startAttendance(ctx, scoped, userID, loc, checkin.CreatedAt, checkin.ID)
+	} else {
+		day, err = finishAttendance(ctx, scoped, userID, checkin.CreatedAt, checkin.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fail := func(err error) (*models.Checkin, error) {
+		if err := undoAttendance(ctx, scoped, day, req.Type); err != nil {
+			log.Printf("Reverting attendance %s: %v", day.ID.Hex(), err)
+		}
+		return nil, err
+	}
+	var stored *models.Upload
+	if req.SelfieID == "" && req.SelfieImage != "" {
+		data, err := decodeDataURL(req.SelfieImage)
+		if err == nil {
+			stored, err = storeSelfie(ctx, store, scoped, userID, data, at)
+		}
+		if errors.Is(err, errInvalidImage) {
+			return fail(err)
 		}
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
+		if err != nil {
+			log.Printf("Storing selfie: %v", err)
+			return fail(errSelfieStore)
+		}
+		selfie = stored
+	}
+	if selfie != nil {
+		checkin.SelfieID = &selfie.ID
+		checkin.SelfieKey = selfie.Key
+		checkin.SelfieThumbKey = selfie.ThumbnailKey
+	}
+	if _, err := scoped.Collection("checkins").InsertOne(ctx, &checkin); err != nil {
+		if stored != nil {
+			if err := discardUpload(ctx, scoped, store, *stored); err != nil {
+				log.Printf("Removing upload %s: %v", stored.ID.Hex(), err)
+			}
+		}
+		if mongo.IsDuplicateKeyError(err) && clientID != "" {
+			err = errDuplicateClientID
 		}
-		c.Locals("userId", claims["id"])
-		c.Locals("userRole", claims["role"])
-		return c.Next()
+		return fail(err)
+	}
+	if selfie != nil {
+		if _, err := scoped.Collection("uploads").UpdateOne(ctx, bson.M{"_id": selfie.ID}, bson.M{"$set": bson.M{"checkinId": checkin.ID}}); err != nil {
+			log.Printf("Linking upload %s: %v", selfie.ID.Hex(), err)
+		}
+	}
+	return &checkin, nil
+}
+
+// checkinFailure maps an error from createCheckin to a response status and
+// body.
+func checkinFailure(err error) (int, fiber.Map) {
+	switch {
+	case errors.Is(err, errSelfieUnavailable):
+		return http.StatusBadRequest, fiber.Map{"error": err.Error()}
+	case errors.Is(err, errInvalidImage):
+		return http.StatusUnprocessableEntity, fiber.Map{"error": err.Error()}
+	case errors.Is(err, errDuplicateClientID):
+		return http.StatusConflict, fiber.Map{"error": err.Error(), "code": "duplicate_client_id"}
+	case errors.Is(err, errAlreadyCheckedIn):
+		return http.StatusConflict, fiber.Map{"error": err.Error(), "code": "already_checked_in"}
+	case errors.Is(err, errAlreadyCheckedOut):
+		return http.StatusConflict, fiber.Map{"error": err.Error(), "code": "already_checked_out"}
+	case errors.Is(err, errNotCheckedIn):
+		return http.StatusConflict, fiber.Map{"error": err.Error(), "code": "not_checked_in"}
+	}
+	return http.StatusInternalServerError, fiber.Map{"error": err.Error()}
+}
+
+// listQueryError answers a list request whose query string was rejected.
+func listQueryError(c *fiber.Ctx, err error) error {
+	var bad errBadQuery
+	if errors.As(err, &bad) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": bad.Error()})
 	}
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+}
 
-	// GET /api/checkins - get all checkins (for manager, return all; for member, only their own)
+func RegisterCheckinRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager, store storage.BlobStore, urlTTL time.Duration) {
+	authRequired := authn.Required()
+
+	// GET /api/checkins - daftar check-in per halaman; member hanya melihat miliknya
+	// Query: page, limit, userId, teamId, projectId, type, mood, status, from, to, sort, fields
 	app.Get("/api/checkins", authRequired, func(c *fiber.Ctx) error {
-		userId, ok := c.Locals("userId").(string)
-		if !ok {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-		}
-		userRole, _ := c.Locals("userRole").(string)
-		var filter bson.M
-		if userRole == "manager" || userRole == "project_manager" {
-			filter = bson.M{} // all data
-		} else {
-			objId, _ := primitive.ObjectIDFromHex(userId)
-			filter = bson.M{"userId": objId}
-		}
-		cur, err := db.Collection("checkins").Find(context.Background(), filter)
+		filter, err := checkinListFilter(c, db)
+		if err != nil {
+			return listQueryError(c, err)
+		}
+		sort, err := checkinSort(c.Query("sort"))
+		if err != nil {
+			return listQueryError(c, err)
+		}
+		projection, err := checkinProjection(c.Query("fields"))
+		if err != nil {
+			return listQueryError(c, err)
+		}
+		page, limit := pageParams(c)
+		ctx := context.Background()
+		checkins := orgDB(c, db).Collection("checkins")
+		total, err := checkins.CountDocuments(ctx, filter)
 		if err != nil {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
-		var checkins []models.Checkin
-		if err := cur.All(context.Background(), &checkins); err != nil {
+		opts := options.Find().SetSort(sort).SetSkip((page - 1) * limit).SetLimit(limit)
+		if projection != nil {
+			opts.SetProjection(projection)
+		}
+		cur, err := checkins.Find(ctx, filter, opts)
+		if err != nil {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
-		return c.JSON(checkins)
+		if projection != nil {
+			docs := []bson.M{}
+			if err := cur.All(ctx, &docs); err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			for _, doc := range docs {
+				projectedCheckin(doc)
+				signCheckinDoc(ctx, store, urlTTL, doc)
+			}
+			return c.JSON(fiber.Map{"data": docs, "total": total, "page": page, "limit": limit})
+		}
+		data := []models.Checkin{}
+		if err := cur.All(ctx, &data); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		signCheckins(ctx, store, urlTTL, data)
+		return c.JSON(fiber.Map{"data": data, "total": total, "page": page, "limit": limit})
 	})
 
-	app.Post("/api/checkins", authRequired, func(c *fiber.Ctx) error {
-		var req struct {
-			Type        string             `json:"type"`
-			Mood        string             `json:"mood"`
-			Description string             `json:"description"`
-			SelfieImage string             `json:"selfieImage"`
-			FaceData    *models.FaceResult `json:"faceData"`
-		}
+	// POST /api/checkins - catat check-in/checkout; aman diulang dengan Idempotency-Key
+	app.Post("/api/checkins", authRequired, verifiedEmailRequired(db), idempotent(db), func(c *fiber.Ctx) error {
+		var req checkinRequest
 		if err := c.BodyParser(&req); err != nil {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
-		userId, ok := c.Locals("userId").(string)
-		if !ok {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-		}
-		objId, _ := primitive.ObjectIDFromHex(userId)
-		status := "present"
-		checkin := models.Checkin{
-			ID:          primitive.NewObjectID(),
-			UserID:      objId,
-			Type:        req.Type,
-			Mood:        req.Mood,
-			SelfieURL:   req.SelfieImage, // base64 string, bisa diubah ke URL jika upload file
-			Description: req.Description,
-			CreatedAt:   time.Now(),
-			FaceResult:  req.FaceData,
-			Status:      status,
-		}
-		_, err := db.Collection("checkins").InsertOne(context.Background(), checkin)
+		p := auth.PrincipalFrom(c)
+		ctx := context.Background()
+		scoped := orgDB(c, db)
+		scale, err := activeMoodScale(ctx, scoped)
 		if err != nil {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
-		return c.Status(http.StatusCreated).JSON(checkin)
+		if errs := req.validate(scale); len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+		checkin, err := createCheckin(ctx, db, store, scoped, p.UserID, &req, time.Now(), "")
+		if err != nil {
+			status, body := checkinFailure(err)
+			return c.Status(status).JSON(body)
+		}
+		created := []models.Checkin{*checkin}
+		signCheckins(ctx, store, urlTTL, created)
+		return c.Status(http.StatusCreated).JSON(created[0])
 	})
 
-	app.Get("/api/checkins/today", authRequired, func(c *fiber.Ctx) error {
-		userId, ok := c.Locals("userId").(string)
-		if !ok {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-		}
-		objId, _ := primitive.ObjectIDFromHex(userId)
-		start := time.Now().Truncate(24 * time.Hour)
-		end := start.Add(24 * time.Hour)
-		cur, err := db.Collection("checkins").Find(context.Background(), bson.M{"userId": objId, "createdAt": bson.M{"$gte": start, "$lt": end}})
+	// Hari dihitung menurut zona waktu user (atau organisasi), bukan UTC
+	checkinsOn := func(c *fiber.Ctx, day func(loc *time.Location) (time.Time, error)) error {
+		p := auth.PrincipalFrom(c)
+		ctx := context.Background()
+		loc, err := userLocation(ctx, db, p.UserID, p.OrgID)
 		if err != nil {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
-		var checkins []models.Checkin
-		if err := cur.All(context.Background(), &checkins); err != nil {
+		t, err := day(loc)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Date must be YYYY-MM-DD"})
+		}
+		start, end := dayBounds(t, loc)
+		cur, err := orgDB(c, db).Collection("checkins").Find(ctx,
+			bson.M{"userId": p.UserID, "createdAt": bson.M{"$gte": start, "$lt": end}},
+			options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+		if err != nil {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
+		checkins := []models.Checkin{}
+		if err := cur.All(ctx, &checkins); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		signCheckins(ctx, store, urlTTL, checkins)
 		return c.JSON(checkins)
+	}
+
+	app.Get("/api/checkins/today", authRequired, func(c *fiber.Ctx) error {
+		return checkinsOn(c, func(*time.Location) (time.Time, error) { return time.Now(), nil })
+	})
+
+	// GET /api/checkins/date/:date - check-in milik user pada tanggal YYYY-MM-DD
+	app.Get("/api/checkins/date/:date", authRequired, func(c *fiber.Ctx) error {
+		return checkinsOn(c, func(loc *time.Location) (time.Time, error) {
+			return time.ParseInLocation(time.DateOnly, c.Params("date"), loc)
+		})
 	})
 }
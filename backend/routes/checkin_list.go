@@ -0,0 +1,285 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"backend/auth"
+	"backend/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// checkinFields maps the JSON field names accepted by ?fields= and ?sort= on
+// check-in listings to their stored names.
+var checkinFields = map[string]string{
+	"id":          "_id",
+	"userId":      "userId",
+	"type":        "type",
+	"mood":        "mood",
+	"moodScore":   "moodScore",
+	"moodAnswer":  "moodAnswer",
+	"selfieUrl":   "selfieUrl",
+	"description": "description",
+	"createdAt":   "createdAt",
+	"faceResult":  "faceResult",
+	"status":      "status",
+}
+
+var checkinSortFields = map[string]bool{"createdAt": true, "type": true, "mood": true, "moodScore": true, "status": true}
+
+// errBadQuery carries a client-facing message for a malformed list query.
+type errBadQuery string
+
+func (e errBadQuery) Error() string { return string(e) }
+
+// EnsureCheckinIndexes backs the filters and sort orders of check-in
+// listings.
+func EnsureCheckinIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("checkins").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: tenant.Field, Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: tenant.Field, Value: 1}, {Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: tenant.Field, Value: 1}, {Key: "type", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: tenant.Field, Value: 1}, {Key: "mood", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: tenant.Field, Value: 1}, {Key: "userId", Value: 1}, {Key: "clientId", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"clientId": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	// Untuk purger retensi selfie
+	_, err = db.Collection("uploads").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: tenant.Field, Value: 1}, {Key: "createdAt", Value: 1}},
+	})
+	return err
+}
+
+// checkinListFilter builds the filter for GET /api/checkins from the query
+// string. Callers without checkins:read_all only see their own check-ins,
+// or those of a team they lead.
+func checkinListFilter(c *fiber.Ctx, db *mongo.Database) (bson.M, error) {
+	ctx := context.Background()
+	p := auth.PrincipalFrom(c)
+	scoped := orgDB(c, db)
+	filter := bson.M{}
+	readAll := p.Can(auth.PermCheckinsReadAll)
+
+	if v := c.Query("userId"); v != "" {
+		ids, err := objectIDList(v)
+		if err != nil {
+			return nil, errBadQuery("Invalid userId")
+		}
+		filter["userId"] = bson.M{"$in": ids}
+	}
+	if v := c.Query("teamId"); v != "" {
+		teamID, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return nil, errBadQuery("Invalid teamId")
+		}
+		var team struct {
+			Lead    primitive.ObjectID   `bson:"lead"`
+			Members []primitive.ObjectID `bson:"members"`
+		}
+		if err := scoped.Collection("teams").FindOne(ctx, bson.M{"_id": teamID}).Decode(&team); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, errBadQuery("Team not found")
+			}
+			return nil, err
+		}
+		if team.Lead == p.UserID {
+			readAll = true
+		}
+		addUserScope(filter, append(team.Members, team.Lead))
+	}
+	if v := c.Query("projectId"); v != "" {
+		projectID, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return nil, errBadQuery("Invalid projectId")
+		}
+		var project struct {
+			Teams []primitive.ObjectID `bson:"teams"`
+		}
+		if err := scoped.Collection("projects").FindOne(ctx, bson.M{"_id": projectID}).Decode(&project); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, errBadQuery("Project not found")
+			}
+			return nil, err
+		}
+		members, err := scoped.Collection("teams").Distinct(ctx, "members", bson.M{"_id": bson.M{"$in": project.Teams}})
+		if err != nil {
+			return nil, err
+		}
+		leads, err := scoped.Collection("teams").Distinct(ctx, "lead", bson.M{"_id": bson.M{"$in": project.Teams}})
+		if err != nil {
+			return nil, err
+		}
+		var ids []primitive.ObjectID
+		for _, v := range append(members, leads...) {
+			if id, ok := v.(primitive.ObjectID); ok {
+				ids = append(ids, id)
+			}
+		}
+		addUserScope(filter, ids)
+	}
+	if !readAll {
+		addUserScope(filter, []primitive.ObjectID{p.UserID})
+	}
+
+	for _, field := range []string{"type", "mood", "status"} {
+		if v := c.Query(field); v != "" {
+			values := strings.Split(v, ",")
+			if field == "mood" {
+				for i, name := range values {
+					if m, ok := normalizeMood(name); ok {
+						values[i] = m.Name
+					}
+				}
+			}
+			filter[field] = bson.M{"$in": values}
+		}
+	}
+
+	created := bson.M{}
+	loc, err := userLocation(ctx, db, p.UserID, p.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	if v := c.Query("from"); v != "" {
+		from, err := parseDateParam(v, false, loc)
+		if err != nil {
+			return nil, errBadQuery("from must be YYYY-MM-DD or RFC 3339")
+		}
+		created["$gte"] = from
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := parseDateParam(v, true, loc)
+		if err != nil {
+			return nil, errBadQuery("to must be YYYY-MM-DD or RFC 3339")
+		}
+		created["$lt"] = to
+	}
+	if len(created) > 0 {
+		filter["createdAt"] = created
+	}
+	return filter, nil
+}
+
+// addUserScope narrows filter to check-ins by ids, intersecting with any
+// user restriction already present.
+func addUserScope(filter bson.M, ids []primitive.ObjectID) {
+	if ids == nil {
+		ids = []primitive.ObjectID{}
+	}
+	if existing, ok := filter["userId"].(bson.M); ok {
+		filter["$and"] = append(asArray(filter["$and"]), bson.M{"userId": existing})
+	}
+	filter["userId"] = bson.M{"$in": ids}
+}
+
+func asArray(v any) bson.A {
+	a, _ := v.(bson.A)
+	return a
+}
+
+// checkinSort parses ?sort=field or ?sort=-field (descending). Ties are
+// broken by _id so pages are stable.
+func checkinSort(v string) (bson.D, error) {
+	if v == "" {
+		v = "-createdAt"
+	}
+	dir := 1
+	if strings.HasPrefix(v, "-") {
+		dir, v = -1, v[1:]
+	}
+	if !checkinSortFields[v] {
+		return nil, errBadQuery("sort must be one of createdAt, type, mood, moodScore, status, optionally prefixed with -")
+	}
+	return bson.D{{Key: v, Value: dir}, {Key: "_id", Value: dir}}, nil
+}
+
+// checkinProjection parses ?fields=. A list of names returns only those
+// fields; names prefixed with - are left out instead, e.g.
+// fields=-selfieUrl. The two forms cannot be mixed.
+func checkinProjection(v string) (bson.M, error) {
+	if v == "" {
+		return nil, nil
+	}
+	projection := bson.M{}
+	include, exclude := 0, 0
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		value := 1
+		if strings.HasPrefix(name, "-") {
+			value, name = 0, name[1:]
+			exclude++
+		} else {
+			include++
+		}
+		stored, ok := checkinFields[name]
+		if !ok {
+			return nil, errBadQuery("Unknown field " + name)
+		}
+		projection[stored] = value
+		if name == "selfieUrl" {
+			projection["selfieKey"] = value
+			projection["selfieThumbKey"] = value
+		}
+	}
+	if include > 0 && exclude > 0 {
+		return nil, errBadQuery("fields cannot mix included and excluded names")
+	}
+	if include > 0 {
+		if _, ok := projection["_id"]; !ok {
+			projection["_id"] = 0
+		}
+	}
+	return projection, nil
+}
+
+// projectedCheckin renames _id for documents read with a projection, so
+// the response uses the same names as a full check-in.
+func projectedCheckin(doc bson.M) bson.M {
+	if id, ok := doc["_id"]; ok {
+		doc["id"] = id
+		delete(doc, "_id")
+	}
+	delete(doc, tenant.Field)
+	return doc
+}
+
+// parseDateParam accepts a date (YYYY-MM-DD, in loc) or an RFC 3339 time.
+// With endOfDay a bare date means the start of the next day, so ranges
+// include the whole of their last day.
+func parseDateParam(v string, endOfDay bool, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, v, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func objectIDList(v string) ([]primitive.ObjectID, error) {
+	var ids []primitive.ObjectID
+	for _, s := range strings.Split(v, ",") {
+		id, err := primitive.ObjectIDFromHex(strings.TrimSpace(s))
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,125 @@
+package routes
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCheckinSort(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    bson.D
+		wantErr bool
+	}{
+		{"", bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}, false},
+		{"createdAt", bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}, false},
+		{"-moodScore", bson.D{{Key: "moodScore", Value: -1}, {Key: "_id", Value: -1}}, false},
+		{"selfieUrl", nil, true},
+		{"-", nil, true},
+		{"--createdAt", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := checkinSort(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkinSort(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("checkinSort(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCheckinProjection(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    bson.M
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"mood,createdAt", bson.M{"mood": 1, "createdAt": 1, "_id": 0}, false},
+		{"id, mood", bson.M{"_id": 1, "mood": 1}, false},
+		{"-description", bson.M{"description": 0}, false},
+		{"selfieUrl", bson.M{"selfieUrl": 1, "selfieKey": 1, "selfieThumbKey": 1, "_id": 0}, false},
+		{"-selfieUrl", bson.M{"selfieUrl": 0, "selfieKey": 0, "selfieThumbKey": 0}, false},
+		{"mood,-description", nil, true},
+		{"password", nil, true},
+		{"selfieKey", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := checkinProjection(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkinProjection(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("checkinProjection(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseDateParam(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	tests := []struct {
+		in       string
+		endOfDay bool
+		want     time.Time
+		wantErr  bool
+	}{
+		{"2024-05-01", false, time.Date(2024, 5, 1, 0, 0, 0, 0, jakarta), false},
+		{"2024-05-01", true, time.Date(2024, 5, 2, 0, 0, 0, 0, jakarta), false},
+		{"2024-05-01T10:00:00Z", true, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), false},
+		{"01-05-2024", false, time.Time{}, true},
+		{"yesterday", false, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseDateParam(tt.in, tt.endOfDay, jakarta)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDateParam(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseDateParam(%q, %v) = %s, want %s", tt.in, tt.endOfDay, got, tt.want)
+		}
+	}
+}
+
+func TestAddUserScope(t *testing.T) {
+	a, b := primitive.NewObjectID(), primitive.NewObjectID()
+
+	filter := bson.M{}
+	addUserScope(filter, nil)
+	if want := (bson.M{"userId": bson.M{"$in": []primitive.ObjectID{}}}); !reflect.DeepEqual(filter, want) {
+		t.Errorf("empty scope = %v, want %v", filter, want)
+	}
+
+	// ?userId= lalu batas tim lalu batas milik sendiri harus saling memotong
+	filter = bson.M{"userId": bson.M{"$in": []primitive.ObjectID{a, b}}}
+	addUserScope(filter, []primitive.ObjectID{a})
+	addUserScope(filter, []primitive.ObjectID{b})
+	want := bson.M{
+		"userId": bson.M{"$in": []primitive.ObjectID{b}},
+		"$and": bson.A{
+			bson.M{"userId": bson.M{"$in": []primitive.ObjectID{a, b}}},
+			bson.M{"userId": bson.M{"$in": []primitive.ObjectID{a}}},
+		},
+	}
+	if !reflect.DeepEqual(filter, want) {
+		t.Errorf("intersected scope = %v, want %v", filter, want)
+	}
+}
+
+func TestObjectIDList(t *testing.T) {
+	a, b := primitive.NewObjectID(), primitive.NewObjectID()
+	got, err := objectIDList(a.Hex() + ", " + b.Hex())
+	if err != nil || !reflect.DeepEqual(got, []primitive.ObjectID{a, b}) {
+		t.Errorf("objectIDList = %v, %v", got, err)
+	}
+	if _, err := objectIDList(a.Hex() + ",nope"); err == nil {
+		t.Error("objectIDList accepted an invalid id")
+	}
+}
@@ -0,0 +1,185 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"backend/auth"
+	"backend/models"
+	"backend/storage"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	maxSyncBatch      = 50
+	maxClientIDLength = 64
+	// maxClockSkew is how far ahead of the server a client's clock may run.
+	maxClockSkew = 5 * time.Minute
+)
+
+// Outcomes of one synced check-in. Failed items hit a server error and
+// can be sent again; rejected ones cannot succeed as they are.
+const (
+	syncCreated   = "created"
+	syncDuplicate = "duplicate"
+	syncRejected  = "rejected"
+	syncFailed    = "failed"
+)
+
+// syncItem is a check-in recorded offline, with the client's id and
+// timestamp.
+type syncItem struct {
+	checkinRequest
+	ClientID  string     `json:"clientId"`
+	CreatedAt *time.Time `json:"createdAt"`
+}
+
+type syncResult struct {
+	ClientID string          `json:"clientId"`
+	Status   string          `json:"status"`
+	Checkin  *models.Checkin `json:"checkin,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Code     string          `json:"code,omitempty"`
+	Fields   []fieldError    `json:"fields,omitempty"`
+}
+
+// prepareSync validates a batch and returns a result per item, with the
+// rejected ones filled in, and the indexes of the valid items in the order
+// to apply them: by createdAt, keeping request order for equal times.
+func prepareSync(items []syncItem, scale *models.MoodScale, now time.Time, maxAge time.Duration) ([]syncResult, []int) {
+	results := make([]syncResult, len(items))
+	seen := map[string]bool{}
+	var valid []int
+	for i := range items {
+		item := &items[i]
+		item.ClientID = strings.TrimSpace(item.ClientID)
+		results[i].ClientID = item.ClientID
+		var errs []fieldError
+		switch {
+		case item.ClientID == "" || len(item.ClientID) > maxClientIDLength:
+			errs = append(errs, fieldError{"clientId", fmt.Sprintf("must be between 1 and %d characters", maxClientIDLength)})
+		case seen[item.ClientID]:
+			errs = append(errs, fieldError{"clientId", "is repeated in this batch"})
+		}
+		seen[item.ClientID] = true
+		switch {
+		case item.CreatedAt == nil:
+			errs = append(errs, fieldError{"createdAt", "is required"})
+		case item.CreatedAt.After(now.Add(maxClockSkew)):
+			errs = append(errs, fieldError{"createdAt", "is in the future"})
+		case now.Sub(*item.CreatedAt) > maxAge:
+			errs = append(errs, fieldError{"createdAt", fmt.Sprintf("is older than the %s sync window", maxAge)})
+		}
+		errs = append(errs, item.validate(scale)...)
+		if len(errs) > 0 {
+			results[i].Status, results[i].Error, results[i].Code, results[i].Fields = syncRejected, "Validation failed", "validation_failed", errs
+			continue
+		}
+		valid = append(valid, i)
+	}
+
+	// Urut waktu agar aturan checkin -> checkout berlaku seperti saat online
+	sort.SliceStable(valid, func(a, b int) bool {
+		return items[valid[a]].CreatedAt.Before(*items[valid[b]].CreatedAt)
+	})
+	return results, valid
+}
+
+func RegisterCheckinSyncRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager, store storage.BlobStore, urlTTL time.Duration) {
+	maxAge := 72 * time.Hour
+	if d, err := time.ParseDuration(os.Getenv("CHECKIN_SYNC_MAX_AGE")); err == nil && d > 0 {
+		maxAge = d
+	}
+
+	// POST /api/checkins/sync - kirim check-in yang dicatat saat offline
+	// Body: {checkins: [{clientId, createdAt, type, mood, ...}]}; diterapkan
+	// urut createdAt, hasil per item dalam urutan request
+	app.Post("/api/checkins/sync", authn.Required(), verifiedEmailRequired(db), idempotent(db), func(c *fiber.Ctx) error {
+		var req struct {
+			Checkins []syncItem `json:"checkins"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if len(req.Checkins) == 0 || len(req.Checkins) > maxSyncBatch {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("checkins must hold between 1 and %d items", maxSyncBatch)})
+		}
+		p := auth.PrincipalFrom(c)
+		ctx := context.Background()
+		scoped := orgDB(c, db)
+		scale, err := activeMoodScale(ctx, scoped)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		results, valid := prepareSync(req.Checkins, scale, time.Now(), maxAge)
+		for _, i := range valid {
+			item := &req.Checkins[i]
+			var existing models.Checkin
+			err := scoped.Collection("checkins").FindOne(ctx, bson.M{"userId": p.UserID, "clientId": item.ClientID}).Decode(&existing)
+			if err == nil {
+				results[i].Status, results[i].Checkin = syncDuplicate, &existing
+				continue
+			}
+			if !errors.Is(err, mongo.ErrNoDocuments) {
+				results[i].Status, results[i].Error = syncFailed, err.Error()
+				continue
+			}
+			checkin, err := createCheckin(ctx, db, store, scoped, p.UserID, &item.checkinRequest, *item.CreatedAt, item.ClientID)
+			if errors.Is(err, errDuplicateClientID) {
+				// Request lain dengan clientId yang sama menang lebih dulu
+				if err := scoped.Collection("checkins").FindOne(ctx, bson.M{"userId": p.UserID, "clientId": item.ClientID}).Decode(&existing); err == nil {
+					results[i].Status, results[i].Checkin = syncDuplicate, &existing
+					continue
+				}
+			}
+			if err != nil {
+				status, body := checkinFailure(err)
+				results[i].Status = syncRejected
+				if status >= http.StatusInternalServerError {
+					results[i].Status = syncFailed
+				}
+				results[i].Error, _ = body["error"].(string)
+				results[i].Code, _ = body["code"].(string)
+				continue
+			}
+			results[i].Status, results[i].Checkin = syncCreated, checkin
+		}
+
+		var signed []models.Checkin
+		var signedAt []int
+		counts := map[string]int{syncCreated: 0, syncDuplicate: 0, syncRejected: 0, syncFailed: 0}
+		for i, r := range results {
+			counts[r.Status]++
+			if r.Checkin != nil {
+				signed = append(signed, *r.Checkin)
+				signedAt = append(signedAt, i)
+			}
+		}
+		signCheckins(ctx, store, urlTTL, signed)
+		for n, i := range signedAt {
+			results[i].Checkin = &signed[n]
+		}
+		if counts[syncFailed] > 0 {
+			// Item yang gagal dikirim ulang dengan Idempotency-Key yang sama;
+			// yang sudah tersimpan akan kembali sebagai duplicate
+			c.Locals(idempotencyRetryable, true)
+		}
+		return c.JSON(fiber.Map{
+			"results":    results,
+			"created":    counts[syncCreated],
+			"duplicates": counts[syncDuplicate],
+			"rejected":   counts[syncRejected],
+			"failed":     counts[syncFailed],
+		})
+	})
+}
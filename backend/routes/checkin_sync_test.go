@@ -0,0 +1,80 @@
+package routes
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPrepareSync(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	item := func(clientID string, createdAt *time.Time, typ string) syncItem {
+		return syncItem{checkinRequest: checkinRequest{Type: typ, Mood: "Happy"}, ClientID: clientID, CreatedAt: createdAt}
+	}
+	items := []syncItem{
+		item("out", at(-time.Hour), "checkout"),
+		item(" in ", at(-9*time.Hour), "checkin"),
+		item("in", at(-8*time.Hour), "checkin"),       // diulang dalam batch
+		item("late", at(-73*time.Hour), "checkin"),    // di luar jendela sync
+		item("skewed", at(4*time.Minute), "checkout"), // jam klien sedikit maju
+		item("future", at(10*time.Minute), "checkin"), // terlalu jauh ke depan
+		item("", at(-time.Hour), "checkin"),           // tanpa clientId
+		item("undated", nil, "checkin"),               // tanpa createdAt
+		item("bad", at(-2*time.Hour), "lunch"),        // tipe tidak dikenal
+		item("tie", at(-time.Hour), "checkin"),        // waktu sama dengan "out"
+	}
+	results, valid := prepareSync(items, nil, now, 72*time.Hour)
+
+	// Urut createdAt, yang waktunya sama tetap urut request
+	if want := []int{1, 0, 9, 4}; !reflect.DeepEqual(valid, want) {
+		t.Errorf("valid = %v, want %v", valid, want)
+	}
+	if items[1].ClientID != "in" || results[1].ClientID != "in" {
+		t.Errorf("clientId was not trimmed: %q", items[1].ClientID)
+	}
+	rejected := map[int][]string{
+		2: {"clientId"},
+		3: {"createdAt"},
+		5: {"createdAt"},
+		6: {"clientId"},
+		7: {"createdAt"},
+		8: {"type"},
+	}
+	for i, r := range results {
+		want, isRejected := rejected[i]
+		if !isRejected {
+			if r.Status != "" {
+				t.Errorf("item %d (%q) status = %q before it was applied", i, r.ClientID, r.Status)
+			}
+			continue
+		}
+		if r.Status != syncRejected || r.Code != "validation_failed" {
+			t.Errorf("item %d (%q) = %s/%s, want rejected", i, r.ClientID, r.Status, r.Code)
+		}
+		if got := errorFields(r.Fields); !reflect.DeepEqual(got, want) {
+			t.Errorf("item %d (%q) errors on %v, want %v", i, r.ClientID, got, want)
+		}
+	}
+}
+
+func TestSyncItemDecoding(t *testing.T) {
+	var req struct {
+		Checkins []syncItem `json:"checkins"`
+	}
+	body := `{"checkins":[{"clientId":"a1","createdAt":"2024-05-10T08:00:00+07:00","type":"checkin","mood":"happy","description":"pagi"}]}`
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+	got := req.Checkins[0]
+	if got.ClientID != "a1" || got.Type != "checkin" || got.Mood != "happy" || got.Description != "pagi" {
+		t.Errorf("decoded %+v", got)
+	}
+	if want := time.Date(2024, 5, 10, 1, 0, 0, 0, time.UTC); got.CreatedAt == nil || !got.CreatedAt.Equal(want) {
+		t.Errorf("createdAt = %v, want %v", got.CreatedAt, want)
+	}
+}
@@ -0,0 +1,163 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func errorFields(errs []fieldError) []string {
+	fields := []string{}
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	return fields
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestCheckinRequestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       checkinRequest
+		fields    []string
+		wantMood  string
+		wantScore *int
+	}{
+		{"canonical mood", checkinRequest{Type: "checkin", Mood: " happy "}, []string{}, "Happy", intPtr(1)},
+		{"mood from face expression", checkinRequest{Type: "checkout", FaceData: &models.FaceResult{Expression: "Sad", Age: 30}}, []string{}, "Stressed", intPtr(-1)},
+		{"given mood wins over face", checkinRequest{Type: "checkin", Mood: "neutral", FaceData: &models.FaceResult{Expression: "happy"}}, []string{}, "Neutral", intPtr(0)},
+		{"check-in without mood", checkinRequest{Type: "checkin", Mood: "unknown"}, []string{}, "", nil},
+		{"checkout without mood", checkinRequest{Type: "checkout"}, []string{"mood"}, "", nil},
+		{"unknown mood", checkinRequest{Type: "checkin", Mood: "ecstatic"}, []string{"mood"}, "ecstatic", nil},
+		{"unknown type", checkinRequest{Type: "lunch", Mood: "Happy"}, []string{"type"}, "Happy", intPtr(1)},
+		{
+			"bad face data",
+			checkinRequest{Type: "checkin", Mood: "Happy", FaceData: &models.FaceResult{Age: 200, Gender: "x", Expression: "bored"}},
+			[]string{"faceData.age", "faceData.gender", "faceData.expression"}, "Happy", intPtr(1),
+		},
+		{"scale answer without scale", checkinRequest{Type: "checkin", MoodValue: intPtr(1)}, []string{"moodValue"}, "", nil},
+		{"description too long", checkinRequest{Type: "checkin", Mood: "Happy", Description: strings.Repeat("a", 501)}, []string{"description"}, "Happy", intPtr(1)},
+		{"description counted in runes", checkinRequest{Type: "checkin", Mood: "Happy", Description: strings.Repeat("é", 500)}, []string{}, "Happy", intPtr(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			errs := req.validate(nil)
+			if got := errorFields(errs); !reflect.DeepEqual(got, tt.fields) {
+				t.Fatalf("errors on %v, want %v", got, tt.fields)
+			}
+			if req.Mood != tt.wantMood {
+				t.Errorf("mood = %q, want %q", req.Mood, tt.wantMood)
+			}
+			if !reflect.DeepEqual(req.moodScore, tt.wantScore) {
+				t.Errorf("moodScore = %v, want %v", req.moodScore, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestCheckinRequestValidateScale(t *testing.T) {
+	scale := &models.MoodScale{
+		ID:      primitive.NewObjectID(),
+		Version: 3,
+		Options: []models.MoodOption{
+			{Value: 2, Label: "Great", Mood: "Happy"},
+			{Value: 0, Label: "Meh", Mood: "Neutral"},
+			{Value: -2, Label: "Bad", Mood: "Stressed"},
+			{Value: 5, Label: "Other"},
+		},
+		Questions: []models.MoodQuestion{
+			{Key: "energy", Label: "Energy", Kind: models.QuestionSlider, Min: 1, Max: 5, Required: true},
+			{Key: "focus", Label: "Focus", Kind: models.QuestionChoice, Options: []models.MoodOption{{Value: 1, Label: "Low"}, {Value: 2, Label: "High"}}},
+			{Key: "note", Label: "Note", Kind: models.QuestionText},
+		},
+	}
+	tests := []struct {
+		name      string
+		req       checkinRequest
+		fields    []string
+		wantLabel string
+		wantMood  string
+	}{
+		{"by value", checkinRequest{Type: "checkin", MoodValue: intPtr(2), Answers: map[string]any{"energy": 3.0}}, []string{}, "Great", "Happy"},
+		{"by label", checkinRequest{Type: "checkin", Mood: "meh", Answers: map[string]any{"energy": 1.0}}, []string{}, "Meh", "Neutral"},
+		{"by canonical mood", checkinRequest{Type: "checkin", Mood: "sad", Answers: map[string]any{"energy": 5.0}}, []string{}, "Bad", "Stressed"},
+		{"option without mood", checkinRequest{Type: "checkin", MoodValue: intPtr(5), Answers: map[string]any{"energy": 5.0}}, []string{}, "Other", ""},
+		{"all questions", checkinRequest{Type: "checkout", MoodValue: intPtr(0), Answers: map[string]any{"energy": 2.0, "focus": 2.0, "note": " ok "}}, []string{}, "Meh", "Neutral"},
+		{"unknown value", checkinRequest{Type: "checkin", MoodValue: intPtr(7)}, []string{"moodValue"}, "", ""},
+		{"unknown mood", checkinRequest{Type: "checkin", Mood: "ecstatic"}, []string{"mood"}, "", ""},
+		{"checkout without answer", checkinRequest{Type: "checkout"}, []string{"moodValue"}, "", ""},
+		{"check-in without answer", checkinRequest{Type: "checkin"}, []string{}, "", ""},
+		{"answers without mood", checkinRequest{Type: "checkin", Answers: map[string]any{"energy": 3.0}}, []string{"moodValue"}, "", ""},
+		{"required question missing", checkinRequest{Type: "checkin", MoodValue: intPtr(2)}, []string{"answers.energy"}, "Great", "Happy"},
+		{"slider out of range", checkinRequest{Type: "checkin", MoodValue: intPtr(2), Answers: map[string]any{"energy": 9.0}}, []string{"answers.energy"}, "Great", "Happy"},
+		{"slider not whole", checkinRequest{Type: "checkin", MoodValue: intPtr(2), Answers: map[string]any{"energy": 2.5}}, []string{"answers.energy"}, "Great", "Happy"},
+		{"choice not an option", checkinRequest{Type: "checkin", MoodValue: intPtr(2), Answers: map[string]any{"energy": 3.0, "focus": 3.0}}, []string{"answers.focus"}, "Great", "Happy"},
+		{"text not a string", checkinRequest{Type: "checkin", MoodValue: intPtr(2), Answers: map[string]any{"energy": 3.0, "note": 4.0}}, []string{"answers.note"}, "Great", "Happy"},
+		{"unknown question", checkinRequest{Type: "checkin", MoodValue: intPtr(2), Answers: map[string]any{"energy": 3.0, "sleep": 8.0}}, []string{"answers.sleep"}, "Great", "Happy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			errs := req.validate(scale)
+			if got := errorFields(errs); !reflect.DeepEqual(got, tt.fields) {
+				t.Fatalf("errors on %v, want %v", got, tt.fields)
+			}
+			if len(errs) > 0 {
+				return
+			}
+			if req.Mood != tt.wantMood {
+				t.Errorf("mood = %q, want %q", req.Mood, tt.wantMood)
+			}
+			if tt.wantLabel == "" {
+				if req.moodAnswer != nil {
+					t.Errorf("moodAnswer = %+v, want none", req.moodAnswer)
+				}
+				return
+			}
+			a := req.moodAnswer
+			if a == nil || a.Label != tt.wantLabel || a.ScaleID != scale.ID || a.ScaleVersion != scale.Version {
+				t.Fatalf("moodAnswer = %+v, want label %q of scale version %d", a, tt.wantLabel, scale.Version)
+			}
+		})
+	}
+}
+
+func TestCheckinFailure(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{errSelfieUnavailable, http.StatusBadRequest, ""},
+		{errInvalidImage, http.StatusUnprocessableEntity, ""},
+		{fmt.Errorf("storing: %w", errInvalidImage), http.StatusUnprocessableEntity, ""},
+		{errDuplicateClientID, http.StatusConflict, "duplicate_client_id"},
+		{errAlreadyCheckedIn, http.StatusConflict, "already_checked_in"},
+		{errAlreadyCheckedOut, http.StatusConflict, "already_checked_out"},
+		{errNotCheckedIn, http.StatusConflict, "not_checked_in"},
+		{errSelfieStore, http.StatusInternalServerError, ""},
+		{errors.New("connection reset"), http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		status, body := checkinFailure(tt.err)
+		if status != tt.status {
+			t.Errorf("checkinFailure(%v) status = %d, want %d", tt.err, status, tt.status)
+		}
+		code, _ := body["code"].(string)
+		if code != tt.code {
+			t.Errorf("checkinFailure(%v) code = %q, want %q", tt.err, code, tt.code)
+		}
+		if body["error"] != tt.err.Error() {
+			t.Errorf("checkinFailure(%v) error = %v", tt.err, body["error"])
+		}
+	}
+}
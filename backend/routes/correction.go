@@ -0,0 +1,637 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"backend/auth"
+	"backend/models"
+	"backend/storage"
+	"backend/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultEditWindow = 15 * time.Minute
+	// maxCorrectionAge is how far back a correction may move or add a
+	// check-in.
+	maxCorrectionAge = 30 * 24 * time.Hour
+)
+
+// correctionError explains why a correction cannot be applied to the
+// current attendance records.
+type correctionError string
+
+func (e correctionError) Error() string { return string(e) }
+
+// EnsureCorrectionIndexes backs the correction queue and revision history.
+func EnsureCorrectionIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("checkin_corrections").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: tenant.Field, Value: 1}, {Key: "status", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: tenant.Field, Value: 1}, {Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = db.Collection("checkin_revisions").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: tenant.Field, Value: 1}, {Key: "checkinId", Value: 1}, {Key: "createdAt", Value: 1}},
+	})
+	return err
+}
+
+// leadsUser reports whether leadID leads a team userID belongs to.
+func leadsUser(ctx context.Context, scoped *tenant.DB, leadID, userID primitive.ObjectID) (bool, error) {
+	n, err := scoped.Collection("teams").CountDocuments(ctx, bson.M{"lead": leadID, "members": userID})
+	return n > 0, err
+}
+
+// saveRevision stores the check-in as it was before a change.
+func saveRevision(ctx context.Context, scoped *tenant.DB, before models.Checkin, by primitive.ObjectID, action string, correctionID *primitive.ObjectID) error {
+	rev := models.CheckinRevision{
+		ID:           primitive.NewObjectID(),
+		CheckinID:    before.ID,
+		UserID:       before.UserID,
+		ChangedBy:    by,
+		Action:       action,
+		CorrectionID: correctionID,
+		Before:       before,
+		CreatedAt:    time.Now(),
+	}
+	_, err := scoped.Collection("checkin_revisions").InsertOne(ctx, &rev)
+	return err
+}
+
+// correctTime checks that checkin can move to at without breaking its
+// attendance record. With apply the attendance record is updated too.
+func correctTime(ctx context.Context, scoped *tenant.DB, checkin models.Checkin, at time.Time, apply bool) error {
+	col := scoped.Collection("attendance")
+	field := "checkinId"
+	if checkin.Type == models.CheckinTypeOut {
+		field = "checkoutId"
+	}
+	var day models.Attendance
+	err := col.FindOne(ctx, bson.M{field: checkin.ID}).Decode(&day)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return correctionError("The check-in has no attendance record to correct")
+	}
+	if err != nil {
+		return err
+	}
+	set, err := correctedAttendance(day, checkin.Type, at)
+	if err != nil || !apply {
+		return err
+	}
+	set["updatedAt"] = time.Now()
+	_, err = col.UpdateOne(ctx, bson.M{"_id": day.ID}, bson.M{"$set": set})
+	return err
+}
+
+// correctedAttendance returns the fields of day that change when its
+// check-in or checkout moves to at: a check-in stays on its day and before
+// the checkout, a checkout stays within maxShiftLength after the check-in.
+func correctedAttendance(day models.Attendance, checkinType string, at time.Time) (bson.M, error) {
+	set := bson.M{}
+	if checkinType == models.CheckinTypeIn {
+		loc, err := loadTimezone(day.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		if at.In(loc).Format(time.DateOnly) != day.Date {
+			return nil, correctionError("A check-in can only be moved within its own day")
+		}
+		if day.CheckoutAt != nil && !at.Before(*day.CheckoutAt) {
+			return nil, correctionError("A check-in must stay before its checkout")
+		}
+		set["checkinAt"] = at
+		if day.CheckoutAt != nil {
+			set["workedSeconds"] = int64(day.CheckoutAt.Sub(at) / time.Second)
+		}
+		return set, nil
+	}
+	if day.CheckinAt == nil || !at.After(*day.CheckinAt) || at.Sub(*day.CheckinAt) > maxShiftLength {
+		return nil, correctionError("A checkout must be after its check-in and within 24 hours of it")
+	}
+	set["checkoutAt"] = at
+	set["workedSeconds"] = int64(at.Sub(*day.CheckinAt) / time.Second)
+	return set, nil
+}
+
+// applyCorrection carries out an approved correction.
+func applyCorrection(ctx context.Context, db *mongo.Database, scoped *tenant.DB, corr models.CheckinCorrection, by primitive.ObjectID) error {
+	now := time.Now()
+	if corr.CheckinID != nil {
+		var checkin models.Checkin
+		err := scoped.Collection("checkins").FindOne(ctx, bson.M{"_id": *corr.CheckinID, "userId": corr.UserID}).Decode(&checkin)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return correctionError("The check-in no longer exists")
+		}
+		if err != nil {
+			return err
+		}
+		set := bson.M{"editedAt": now}
+		if corr.At != nil {
+			if err := correctTime(ctx, scoped, checkin, *corr.At, false); err != nil {
+				return err
+			}
+			set["createdAt"] = *corr.At
+		}
+		if corr.Description != nil {
+			set["description"] = *corr.Description
+		}
+		if err := saveRevision(ctx, scoped, checkin, by, models.RevisionCorrected, &corr.ID); err != nil {
+			return err
+		}
+		if corr.At != nil {
+			if err := correctTime(ctx, scoped, checkin, *corr.At, true); err != nil {
+				return err
+			}
+		}
+		if _, err := scoped.Collection("checkins").UpdateOne(ctx, bson.M{"_id": checkin.ID}, bson.M{"$set": set}); err != nil {
+			// Kembalikan kehadiran agar tetap sesuai dengan check-in
+			if corr.At != nil {
+				if rerr := correctTime(ctx, scoped, checkin, checkin.CreatedAt, true); rerr != nil {
+					log.Printf("Reverting attendance of check-in %s: %v", checkin.ID.Hex(), rerr)
+				}
+			}
+			return err
+		}
+		return nil
+	}
+
+	// Check-in atau checkout yang terlupa
+	checkin := models.Checkin{
+		ID:           primitive.NewObjectID(),
+		UserID:       corr.UserID,
+		Type:         corr.Type,
+		CreatedAt:    *corr.At,
+		Status:       models.CheckinStatusPresent,
+		CorrectionID: &corr.ID,
+	}
+	if corr.Description != nil {
+		checkin.Description = *corr.Description
+	}
+	var day *models.Attendance
+	var err error
+	if corr.Type == models.CheckinTypeIn {
+		loc, lerr := userLocation(ctx, db, corr.UserID, scoped.OrgID())
+		if lerr != nil {
+			return lerr
+		}
+		day, err = startAttendance(ctx, scoped, corr.UserID, loc, checkin.CreatedAt, checkin.ID)
+	} else {
+		day, err = finishAttendance(ctx, scoped, corr.UserID, checkin.CreatedAt, checkin.ID)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := scoped.Collection("checkins").InsertOne(ctx, &checkin); err != nil {
+		if err := undoAttendance(ctx, scoped, day, corr.Type); err != nil {
+			log.Printf("Reverting attendance %s: %v", day.ID.Hex(), err)
+		}
+		return err
+	}
+	return nil
+}
+
+func RegisterCorrectionRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager, store storage.BlobStore, urlTTL time.Duration) {
+	authRequired := authn.Required()
+	editWindow := defaultEditWindow
+	if d, err := time.ParseDuration(os.Getenv("CHECKIN_EDIT_WINDOW")); err == nil && d >= 0 {
+		editWindow = d
+	}
+
+	// ownCheckin loads the caller's :id check-in if it may still be edited.
+	// When it returns nil the error response has been written.
+	ownCheckin := func(c *fiber.Ctx) (*models.Checkin, error) {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return nil, c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid check-in id"})
+		}
+		var checkin models.Checkin
+		err = orgDB(c, db).Collection("checkins").FindOne(context.Background(), bson.M{"_id": id, "userId": auth.PrincipalFrom(c).UserID}).Decode(&checkin)
+		if err != nil {
+			return nil, c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Check-in not found"})
+		}
+		if checkin.Status == models.CheckinStatusAbsent {
+			return nil, c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Absences cannot be changed, request a correction instead", "code": "checkin_absent"})
+		}
+		if time.Since(checkin.CreatedAt) > editWindow {
+			return nil, c.Status(http.StatusConflict).JSON(fiber.Map{"error": "The edit window has passed, request a correction instead", "code": "edit_window_closed"})
+		}
+		return &checkin, nil
+	}
+
+	// PUT /api/checkins/:id - ubah deskripsi/mood check-in sendiri dalam masa tenggang
+	app.Put("/api/checkins/:id", authRequired, func(c *fiber.Ctx) error {
+		var req struct {
+			Description *string        `json:"description"`
+			Mood        *string        `json:"mood"`
+			MoodValue   *int           `json:"moodValue"`
+			Answers     map[string]any `json:"answers"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		moodChanged := req.Mood != nil || req.MoodValue != nil || req.Answers != nil
+		if req.Description == nil && !moodChanged {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "No fields to update"})
+		}
+		checkin, err := ownCheckin(c)
+		if checkin == nil {
+			return err
+		}
+		ctx := context.Background()
+		scoped := orgDB(c, db)
+		edit := checkinRequest{Type: checkin.Type, Description: checkin.Description}
+		if req.Description != nil {
+			edit.Description = *req.Description
+		}
+		var errs []fieldError
+		if moodChanged {
+			scale, err := activeMoodScale(ctx, scoped)
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			if req.Mood != nil {
+				edit.Mood = *req.Mood
+			}
+			edit.MoodValue, edit.Answers = req.MoodValue, req.Answers
+			errs = edit.validate(scale)
+		} else {
+			errs = edit.checkDescription()
+		}
+		if len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+
+		now := time.Now()
+		set := bson.M{"description": edit.Description, "editedAt": now}
+		unset := bson.M{}
+		if moodChanged {
+			set["mood"] = edit.Mood
+			if edit.moodScore != nil {
+				set["moodScore"] = *edit.moodScore
+			} else {
+				unset["moodScore"] = ""
+			}
+			if edit.moodAnswer != nil {
+				set["moodAnswer"] = edit.moodAnswer
+			} else {
+				unset["moodAnswer"] = ""
+			}
+		}
+		update := bson.M{"$set": set}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+		if err := saveRevision(ctx, scoped, *checkin, checkin.UserID, models.RevisionEdited, nil); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		var updated models.Checkin
+		err = scoped.Collection("checkins").FindOneAndUpdate(ctx, bson.M{"_id": checkin.ID}, update,
+			options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		result := []models.Checkin{updated}
+		signCheckins(ctx, store, urlTTL, result)
+		return c.JSON(result[0])
+	})
+
+	// DELETE /api/checkins/:id - hapus check-in sendiri dalam masa tenggang
+	app.Delete("/api/checkins/:id", authRequired, func(c *fiber.Ctx) error {
+		checkin, err := ownCheckin(c)
+		if checkin == nil {
+			return err
+		}
+		ctx := context.Background()
+		scoped := orgDB(c, db)
+		attendance := scoped.Collection("attendance")
+		if checkin.Type == models.CheckinTypeIn {
+			var day models.Attendance
+			err := attendance.FindOne(ctx, bson.M{"checkinId": checkin.ID}).Decode(&day)
+			if err == nil && day.Status == models.AttendanceComplete {
+				return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Delete the checkout first", "code": "checkout_exists"})
+			}
+			if err == nil {
+				_, err = attendance.DeleteOne(ctx, bson.M{"_id": day.ID, "status": models.AttendanceOpen})
+			}
+			if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+		} else {
+			var day models.Attendance
+			err := attendance.FindOne(ctx, bson.M{"checkoutId": checkin.ID}).Decode(&day)
+			if err == nil {
+				err = undoAttendance(ctx, scoped, &day, models.CheckinTypeOut)
+			}
+			if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+		}
+		if err := saveRevision(ctx, scoped, *checkin, checkin.UserID, models.RevisionDeleted, nil); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if _, err := scoped.Collection("checkins").DeleteOne(ctx, bson.M{"_id": checkin.ID}); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if checkin.SelfieID != nil {
+			var up models.Upload
+			err := scoped.Collection("uploads").FindOne(ctx, bson.M{"_id": *checkin.SelfieID}).Decode(&up)
+			if err == nil {
+				err = deleteUpload(ctx, scoped, store, up, time.Now())
+			}
+			if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				log.Printf("Removing selfie of check-in %s: %v", checkin.ID.Hex(), err)
+			}
+		}
+		return c.JSON(fiber.Map{"success": true})
+	})
+
+	// GET /api/checkins/:id/revisions - riwayat perubahan check-in
+	app.Get("/api/checkins/:id/revisions", authRequired, func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid check-in id"})
+		}
+		p := auth.PrincipalFrom(c)
+		ctx := context.Background()
+		scoped := orgDB(c, db)
+		cur, err := scoped.Collection("checkin_revisions").Find(ctx, bson.M{"checkinId": id},
+			options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		revisions := []models.CheckinRevision{}
+		if err := cur.All(ctx, &revisions); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		var owner primitive.ObjectID
+		if len(revisions) > 0 {
+			owner = revisions[0].UserID
+		} else {
+			var checkin models.Checkin
+			if err := scoped.Collection("checkins").FindOne(ctx, bson.M{"_id": id}).Decode(&checkin); err != nil {
+				return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Check-in not found"})
+			}
+			owner = checkin.UserID
+		}
+		if owner != p.UserID && !p.Can(auth.PermCheckinsReadAll) {
+			lead, err := leadsUser(ctx, scoped, p.UserID, owner)
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			if !lead {
+				return auth.Forbidden(c)
+			}
+		}
+		return c.JSON(revisions)
+	})
+
+	// POST /api/corrections - ajukan koreksi check-in sendiri
+	// Body: {checkinId, at, description, reason} untuk check-in yang ada, atau
+	// {type, at, description, reason} untuk check-in/checkout yang terlupa
+	app.Post("/api/corrections", authRequired, func(c *fiber.Ctx) error {
+		var req struct {
+			CheckinID   string     `json:"checkinId"`
+			Type        string     `json:"type"`
+			At          *time.Time `json:"at"`
+			Description *string    `json:"description"`
+			Reason      string     `json:"reason"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		p := auth.PrincipalFrom(c)
+		ctx := context.Background()
+		scoped := orgDB(c, db)
+		now := time.Now()
+		corr := models.CheckinCorrection{
+			ID:        primitive.NewObjectID(),
+			UserID:    p.UserID,
+			Type:      req.Type,
+			At:        req.At,
+			Reason:    strings.TrimSpace(req.Reason),
+			Status:    models.CorrectionPending,
+			CreatedAt: now,
+		}
+		var errs []fieldError
+		if corr.Reason == "" || len(corr.Reason) > maxReasonLength {
+			errs = append(errs, fieldError{"reason", "must be between 1 and 500 characters"})
+		}
+		if req.Description != nil {
+			desc := checkinRequest{Description: *req.Description}
+			errs = append(errs, desc.checkDescription()...)
+			corr.Description = &desc.Description
+		}
+		if req.At != nil && (req.At.After(now.Add(time.Minute)) || now.Sub(*req.At) > maxCorrectionAge) {
+			errs = append(errs, fieldError{"at", "must be in the past 30 days"})
+		}
+
+		var checkin models.Checkin
+		if req.CheckinID != "" {
+			id, err := primitive.ObjectIDFromHex(req.CheckinID)
+			if err == nil {
+				err = scoped.Collection("checkins").FindOne(ctx, bson.M{"_id": id, "userId": p.UserID}).Decode(&checkin)
+			}
+			switch {
+			case err != nil:
+				errs = append(errs, fieldError{"checkinId", "does not name one of your check-ins"})
+			case checkin.Status == models.CheckinStatusAbsent:
+				errs = append(errs, fieldError{"checkinId", "absences cannot be corrected, request the missing check-in instead"})
+			default:
+				corr.CheckinID, corr.Type = &checkin.ID, checkin.Type
+			}
+			if req.At == nil && req.Description == nil {
+				errs = append(errs, fieldError{"at", "at or description is required"})
+			}
+		} else {
+			if req.Type != models.CheckinTypeIn && req.Type != models.CheckinTypeOut {
+				errs = append(errs, fieldError{"type", "must be checkin or checkout"})
+			}
+			if req.At == nil {
+				errs = append(errs, fieldError{"at", "is required when adding a missing check-in"})
+			}
+		}
+		if len(errs) == 0 && corr.CheckinID != nil && corr.At != nil {
+			err := correctTime(ctx, scoped, checkin, *corr.At, false)
+			var invalid correctionError
+			if errors.As(err, &invalid) {
+				errs = append(errs, fieldError{"at", invalid.Error()})
+			} else if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+		}
+		if len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+		if corr.CheckinID != nil {
+			pending, err := scoped.Collection("checkin_corrections").CountDocuments(ctx, bson.M{"checkinId": *corr.CheckinID, "status": models.CorrectionPending})
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			if pending > 0 {
+				return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "A correction for this check-in is already pending", "code": "correction_pending"})
+			}
+		}
+		if _, err := scoped.Collection("checkin_corrections").InsertOne(ctx, &corr); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(http.StatusCreated).JSON(corr)
+	})
+
+	// GET /api/corrections?status=&userId= - approver melihat semua, lead melihat
+	// milik anggota timnya, lainnya hanya miliknya
+	app.Get("/api/corrections", authRequired, func(c *fiber.Ctx) error {
+		p := auth.PrincipalFrom(c)
+		ctx := context.Background()
+		scoped := orgDB(c, db)
+		filter := bson.M{}
+		var visible map[primitive.ObjectID]bool
+		if !p.Can(auth.PermCorrectionsApprove) {
+			members, err := scoped.Collection("teams").Distinct(ctx, "members", bson.M{"lead": p.UserID})
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			visible = map[primitive.ObjectID]bool{p.UserID: true}
+			ids := bson.A{p.UserID}
+			for _, v := range members {
+				if id, ok := v.(primitive.ObjectID); ok && !visible[id] {
+					visible[id] = true
+					ids = append(ids, id)
+				}
+			}
+			filter["userId"] = bson.M{"$in": ids}
+		}
+		if v := c.Query("userId"); v != "" {
+			id, err := primitive.ObjectIDFromHex(v)
+			if err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid userId"})
+			}
+			if visible != nil && !visible[id] {
+				return auth.Forbidden(c)
+			}
+			filter["userId"] = id
+		}
+		if status := c.Query("status"); status != "" {
+			filter["status"] = status
+		}
+		page, limit := pageParams(c)
+		corrections := scoped.Collection("checkin_corrections")
+		total, err := corrections.CountDocuments(ctx, filter)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		cur, err := corrections.Find(ctx, filter, options.Find().
+			SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+			SetSkip((page-1)*limit).SetLimit(limit))
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		data := []models.CheckinCorrection{}
+		if err := cur.All(ctx, &data); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"data": data, "total": total, "page": page, "limit": limit})
+	})
+
+	// DELETE /api/corrections/:id - tarik kembali koreksi yang masih pending
+	app.Delete("/api/corrections/:id", authRequired, func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid correction id"})
+		}
+		res, err := orgDB(c, db).Collection("checkin_corrections").DeleteOne(context.Background(),
+			bson.M{"_id": id, "userId": auth.PrincipalFrom(c).UserID, "status": models.CorrectionPending})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if res.DeletedCount == 0 {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "No pending correction with this id"})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	})
+
+	// Koreksi ditinjau oleh lead tim pengaju atau pemegang checkins:approve_corrections,
+	// tidak pernah oleh pengaju sendiri
+	review := func(status string) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			id, err := primitive.ObjectIDFromHex(c.Params("id"))
+			if err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid correction id"})
+			}
+			var req struct {
+				Note string `json:"note"`
+			}
+			if len(c.Body()) > 0 {
+				if err := c.BodyParser(&req); err != nil {
+					return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+				}
+			}
+			note := strings.TrimSpace(req.Note)
+			if len(note) > maxReasonLength {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Note must be at most 500 characters"})
+			}
+			p := auth.PrincipalFrom(c)
+			ctx := context.Background()
+			scoped := orgDB(c, db)
+			corrections := scoped.Collection("checkin_corrections")
+			var corr models.CheckinCorrection
+			if err := corrections.FindOne(ctx, bson.M{"_id": id, "status": models.CorrectionPending}).Decode(&corr); err != nil {
+				return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "No pending correction with this id"})
+			}
+			if corr.UserID == p.UserID {
+				return auth.Forbidden(c)
+			}
+			if !p.Can(auth.PermCorrectionsApprove) {
+				lead, err := leadsUser(ctx, scoped, p.UserID, corr.UserID)
+				if err != nil {
+					return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+				}
+				if !lead {
+					return auth.Forbidden(c)
+				}
+			}
+			// Klaim dulu agar dua reviewer tidak menerapkan koreksi yang sama
+			now := time.Now()
+			set := bson.M{"status": status, "reviewedBy": p.UserID, "reviewedAt": now}
+			if note != "" {
+				set["reviewNote"] = note
+			}
+			err = corrections.FindOneAndUpdate(ctx, bson.M{"_id": id, "status": models.CorrectionPending}, bson.M{"$set": set},
+				options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&corr)
+			if err != nil {
+				return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "No pending correction with this id"})
+			}
+			if status == models.CorrectionApproved {
+				if err := applyCorrection(ctx, db, scoped, corr, p.UserID); err != nil {
+					_, rerr := corrections.UpdateOne(ctx, bson.M{"_id": id},
+						bson.M{"$set": bson.M{"status": models.CorrectionPending}, "$unset": bson.M{"reviewedBy": "", "reviewedAt": "", "reviewNote": ""}})
+					if rerr != nil {
+						log.Printf("Reverting correction %s: %v", id.Hex(), rerr)
+					}
+					var invalid correctionError
+					if errors.As(err, &invalid) {
+						return c.Status(http.StatusConflict).JSON(fiber.Map{"error": invalid.Error(), "code": "correction_conflict"})
+					}
+					status, body := checkinFailure(err)
+					return c.Status(status).JSON(body)
+				}
+			}
+			return c.JSON(corr)
+		}
+	}
+
+	app.Post("/api/corrections/:id/approve", authRequired, review(models.CorrectionApproved))
+	app.Post("/api/corrections/:id/reject", authRequired, review(models.CorrectionRejected))
+}
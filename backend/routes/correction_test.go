@@ -0,0 +1,60 @@
+package routes
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCorrectedAttendance(t *testing.T) {
+	wib := time.FixedZone("UTC+7", 7*60*60)
+	clock := func(day, hour, min int) time.Time { return time.Date(2024, 5, day, hour, min, 0, 0, wib) }
+	ptr := func(t time.Time) *time.Time { return &t }
+	open := models.Attendance{Date: "2024-05-10", Timezone: "UTC+7", CheckinAt: ptr(clock(10, 8, 0))}
+	closed := open
+	closed.CheckoutAt = ptr(clock(10, 17, 0))
+
+	tests := []struct {
+		name string
+		day  models.Attendance
+		typ  string
+		at   time.Time
+		want bson.M
+	}{
+		{"earlier check-in, open day", open, models.CheckinTypeIn, clock(10, 7, 30), bson.M{"checkinAt": clock(10, 7, 30)}},
+		{"earlier check-in recomputes hours", closed, models.CheckinTypeIn, clock(10, 7, 0), bson.M{"checkinAt": clock(10, 7, 0), "workedSeconds": int64(10 * 3600)}},
+		{"check-in at local midnight", open, models.CheckinTypeIn, clock(10, 0, 0), bson.M{"checkinAt": clock(10, 0, 0)}},
+		{"check-in moved to the previous day", open, models.CheckinTypeIn, clock(9, 23, 59), nil},
+		{"check-in on the day in UTC but not locally", open, models.CheckinTypeIn, time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC), nil},
+		{"check-in at the checkout", closed, models.CheckinTypeIn, clock(10, 17, 0), nil},
+		{"later checkout", closed, models.CheckinTypeOut, clock(10, 18, 30), bson.M{"checkoutAt": clock(10, 18, 30), "workedSeconds": int64(10*3600 + 1800)}},
+		{"checkout past midnight", closed, models.CheckinTypeOut, clock(11, 2, 0), bson.M{"checkoutAt": clock(11, 2, 0), "workedSeconds": int64(18 * 3600)}},
+		{"checkout before the check-in", closed, models.CheckinTypeOut, clock(10, 7, 0), nil},
+		{"checkout at the check-in", closed, models.CheckinTypeOut, clock(10, 8, 0), nil},
+		{"checkout beyond a shift", closed, models.CheckinTypeOut, clock(11, 8, 1), nil},
+		{"checkout without a check-in", models.Attendance{Date: "2024-05-10"}, models.CheckinTypeOut, clock(10, 17, 0), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := correctedAttendance(tt.day, tt.typ, tt.at)
+			if tt.want == nil {
+				var invalid correctionError
+				if !errors.As(err, &invalid) {
+					t.Fatalf("correctedAttendance = %v, %v, want a correctionError", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("correctedAttendance = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
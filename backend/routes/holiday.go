@@ -0,0 +1,83 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/auth"
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func RegisterHolidayRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager) {
+	authRequired := authn.Required()
+	canManage := auth.Authorize(auth.Has(auth.PermOrgSettings))
+
+	// GET /api/holidays?year=2026 - hari libur organisasi
+	app.Get("/api/holidays", authRequired, func(c *fiber.Ctx) error {
+		filter := bson.M{}
+		if year := c.Query("year"); year != "" {
+			if _, err := time.Parse("2006", year); err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid year"})
+			}
+			filter["date"] = bson.M{"$gte": year + "-01-01", "$lte": year + "-12-31"}
+		}
+		ctx := context.Background()
+		cur, err := orgDB(c, db).Collection("holidays").Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "date", Value: 1}}))
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		holidays := []models.Holiday{}
+		if err := cur.All(ctx, &holidays); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(holidays)
+	})
+
+	app.Post("/api/holidays", authRequired, canManage, func(c *fiber.Ctx) error {
+		var req struct {
+			Date string `json:"date"`
+			Name string `json:"name"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if _, err := time.Parse(time.DateOnly, req.Date); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Date must be YYYY-MM-DD"})
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" || len(name) > maxNameLength {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Name must be between 1 and 100 characters"})
+		}
+		holiday := models.Holiday{ID: primitive.NewObjectID(), Date: req.Date, Name: name, CreatedAt: time.Now()}
+		if _, err := orgDB(c, db).Collection("holidays").InsertOne(context.Background(), &holiday); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "A holiday already exists on this date"})
+			}
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(http.StatusCreated).JSON(holiday)
+	})
+
+	app.Delete("/api/holidays/:id", authRequired, canManage, func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid holiday id"})
+		}
+		res, err := orgDB(c, db).Collection("holidays").DeleteOne(context.Background(), bson.M{"_id": id})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if res.DeletedCount == 0 {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Holiday not found"})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	})
+}
@@ -0,0 +1,180 @@
+package routes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"backend/auth"
+	"backend/models"
+	"backend/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	idempotencyHeader = "Idempotency-Key"
+	maxIdempotencyKey = 255
+	// idempotencyLockTimeout is how long a request may hold its key before a
+	// retry assumes it died with the server and takes over.
+	idempotencyLockTimeout = time.Minute
+	// idempotencyRetryable is set in Locals by handlers whose response
+	// reports failures the client should retry with the same key.
+	idempotencyRetryable = "idempotencyRetryable"
+)
+
+// EnsureIdempotencyIndexes makes keys unique per user and lets MongoDB
+// drop them once they expire.
+func EnsureIdempotencyIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("idempotency_keys").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: tenant.Field, Value: 1}, {Key: "userId", Value: 1}, {Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+// idempotencyReuse is what a request does with a key that is already
+// stored.
+type idempotencyReuse int
+
+const (
+	reuseConflict idempotencyReuse = iota
+	reuseReplay
+	reuseWait
+	reuseTakeOver
+)
+
+// resolveIdempotencyReuse decides how to answer a request whose key is
+// held by prev. Expired keys and keys left processing longer than
+// idempotencyLockTimeout are taken over.
+func resolveIdempotencyReuse(prev models.IdempotencyRecord, fingerprint string, now time.Time) idempotencyReuse {
+	switch {
+	case prev.ExpiresAt.Before(now):
+		return reuseTakeOver
+	case prev.Fingerprint != fingerprint:
+		return reuseConflict
+	case prev.State == models.IdempotencyDone:
+		return reuseReplay
+	case prev.CreatedAt.Before(now.Add(-idempotencyLockTimeout)):
+		return reuseTakeOver
+	}
+	return reuseWait
+}
+
+// idempotent makes a handler safe to retry. A request carrying an
+// Idempotency-Key header is run once per caller and key; retries within
+// IDEMPOTENCY_TTL (default 24h) get the stored response back with
+// Idempotent-Replayed: true. Reusing a key with a different method, path
+// or body is a 409, as is retrying while the first request still runs.
+// Server errors, and responses a handler marks with idempotencyRetryable,
+// are not stored, so they can be retried. It must run after Required.
+func idempotent(db *mongo.Database) fiber.Handler {
+	ttl := 24 * time.Hour
+	if d, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL")); err == nil && d > 0 {
+		ttl = d
+	}
+	return func(c *fiber.Ctx) error {
+		key := c.Get(idempotencyHeader)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKey {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Idempotency-Key must be at most 255 characters"})
+		}
+		p := auth.PrincipalFrom(c)
+		ctx := context.Background()
+		keys := tenant.New(db, p.OrgID).Collection("idempotency_keys")
+		sum := sha256.New()
+		sum.Write([]byte(c.Method() + " " + c.Path() + "\n"))
+		sum.Write(c.Body())
+		fingerprint := hex.EncodeToString(sum.Sum(nil))
+
+		now := time.Now()
+		record := models.IdempotencyRecord{
+			ID:          primitive.NewObjectID(),
+			UserID:      p.UserID,
+			Key:         key,
+			Fingerprint: fingerprint,
+			State:       models.IdempotencyProcessing,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(ttl),
+		}
+		_, err := keys.InsertOne(ctx, &record)
+		if mongo.IsDuplicateKeyError(err) {
+			var prev models.IdempotencyRecord
+			if err := keys.FindOne(ctx, bson.M{"userId": p.UserID, "key": key}).Decode(&prev); err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			switch resolveIdempotencyReuse(prev, fingerprint, now) {
+			case reuseConflict:
+				return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Idempotency-Key was already used for a different request", "code": "idempotency_key_reused"})
+			case reuseReplay:
+				c.Set("Idempotent-Replayed", "true")
+				c.Set(fiber.HeaderContentType, prev.ContentType)
+				return c.Status(prev.Status).Send(prev.Body)
+			case reuseWait:
+				c.Set(fiber.HeaderRetryAfter, "1")
+				return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "A request with this Idempotency-Key is still being processed", "code": "request_in_progress"})
+			}
+			// Ambil alih kunci yang sudah kedaluwarsa (TTL MongoDB belum
+			// menghapusnya) atau ditinggal request yang mati di tengah jalan;
+			// filter yang sama mencegah dua retry mengambil alih bersamaan
+			res, err := keys.UpdateOne(ctx,
+				bson.M{"_id": prev.ID, "$or": bson.A{
+					bson.M{"expiresAt": bson.M{"$lt": now}},
+					bson.M{"state": models.IdempotencyProcessing, "createdAt": bson.M{"$lt": now.Add(-idempotencyLockTimeout)}},
+				}},
+				bson.M{
+					"$set":   bson.M{"fingerprint": fingerprint, "state": models.IdempotencyProcessing, "createdAt": now, "expiresAt": now.Add(ttl)},
+					"$unset": bson.M{"status": "", "contentType": "", "body": ""},
+				})
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			if res.ModifiedCount == 0 {
+				c.Set(fiber.HeaderRetryAfter, "1")
+				return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "A request with this Idempotency-Key is still being processed", "code": "request_in_progress"})
+			}
+			record.ID = prev.ID
+		} else if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		release := func() {
+			if _, err := keys.DeleteOne(ctx, bson.M{"_id": record.ID}); err != nil {
+				log.Printf("Releasing idempotency key: %v", err)
+			}
+		}
+		if err := c.Next(); err != nil {
+			release()
+			return err
+		}
+		status := c.Response().StatusCode()
+		if status >= http.StatusInternalServerError || c.Locals(idempotencyRetryable) == true {
+			release()
+			return nil
+		}
+		_, err = keys.UpdateOne(ctx, bson.M{"_id": record.ID}, bson.M{"$set": bson.M{
+			"state":       models.IdempotencyDone,
+			"status":      status,
+			"contentType": string(c.Response().Header.ContentType()),
+			"body":        c.Response().Body(),
+		}})
+		if err != nil {
+			log.Printf("Storing idempotent response: %v", err)
+			release()
+		}
+		return nil
+	}
+}
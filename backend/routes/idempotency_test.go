@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"testing"
+	"time"
+
+	"backend/models"
+)
+
+func TestResolveIdempotencyReuse(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name        string
+		state       string
+		fingerprint string
+		age         time.Duration // sejak createdAt
+		expiresIn   time.Duration
+		want        idempotencyReuse
+	}{
+		{"finished same request", models.IdempotencyDone, "a", time.Hour, time.Hour, reuseReplay},
+		{"finished other request", models.IdempotencyDone, "b", time.Hour, time.Hour, reuseConflict},
+		{"running same request", models.IdempotencyProcessing, "a", time.Second, time.Hour, reuseWait},
+		{"running other request", models.IdempotencyProcessing, "b", time.Second, time.Hour, reuseConflict},
+		{"running just under the lock timeout", models.IdempotencyProcessing, "a", idempotencyLockTimeout - time.Second, time.Hour, reuseWait},
+		{"abandoned same request", models.IdempotencyProcessing, "a", idempotencyLockTimeout + time.Second, time.Hour, reuseTakeOver},
+		{"abandoned other request", models.IdempotencyProcessing, "b", idempotencyLockTimeout + time.Second, time.Hour, reuseConflict},
+		{"expired finished request", models.IdempotencyDone, "a", 25 * time.Hour, -time.Hour, reuseTakeOver},
+		{"expired other request", models.IdempotencyDone, "b", 25 * time.Hour, -time.Hour, reuseTakeOver},
+		{"expired while running", models.IdempotencyProcessing, "b", time.Second, -time.Second, reuseTakeOver},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := models.IdempotencyRecord{
+				Fingerprint: tt.fingerprint,
+				State:       tt.state,
+				CreatedAt:   now.Add(-tt.age),
+				ExpiresAt:   now.Add(tt.expiresIn),
+			}
+			if got := resolveIdempotencyReuse(prev, "a", now); got != tt.want {
+				t.Errorf("resolveIdempotencyReuse = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"strings"
+)
+
+const (
+	maxSelfieSize   = 5 << 20
+	maxSelfiePixels = 40_000_000
+	thumbnailSize   = 256
+)
+
+var errInvalidImage = errors.New("Image must be a JPEG or PNG of at most 5 MB")
+
+// selfieExtensions are the accepted upload types, detected from content.
+var selfieExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// decodeDataURL accepts "data:image/...;base64,..." or bare base64 as the
+// frontend sends from the webcam capture.
+func decodeDataURL(v string) ([]byte, error) {
+	v = strings.TrimSpace(v)
+	if rest, ok := strings.CutPrefix(v, "data:"); ok {
+		meta, payload, found := strings.Cut(rest, ",")
+		if !found || !strings.HasSuffix(meta, ";base64") {
+			return nil, errInvalidImage
+		}
+		v = payload
+	}
+	if base64.StdEncoding.DecodedLen(len(v)) > maxSelfieSize+3 {
+		return nil, errInvalidImage
+	}
+	data, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		if data, err = base64.RawStdEncoding.DecodeString(v); err != nil {
+			return nil, errInvalidImage
+		}
+	}
+	return data, nil
+}
+
+// inspectImage checks the size and sniffed type of data and decodes it.
+func inspectImage(data []byte) (image.Image, string, error) {
+	if len(data) == 0 || len(data) > maxSelfieSize {
+		return nil, "", errInvalidImage
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := selfieExtensions[contentType]; !ok {
+		return nil, "", errInvalidImage
+	}
+	// Cek dimensi dulu supaya gambar "bom" tidak dialokasikan penuh
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxSelfiePixels {
+		return nil, "", errInvalidImage
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", errInvalidImage
+	}
+	return img, contentType, nil
+}
+
+// thumbnail scales img to fit in a size x size square by averaging the
+// source pixels behind each target pixel, and encodes it as JPEG.
+func thumbnail(img image.Image, size int) ([]byte, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	tw, th := w, h
+	if w > size || h > size {
+		if w >= h {
+			tw, th = size, max(1, h*size/w)
+		} else {
+			tw, th = max(1, w*size/h), size
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+			if x1 == x0 {
+				x1++
+			}
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
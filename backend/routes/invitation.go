@@ -0,0 +1,237 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"backend/auth"
+	"backend/mailer"
+	"backend/models"
+	"backend/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+// inviteOnly reports whether REGISTRATION_MODE=invite, which turns off
+// POST /api/auth/register so accounts can only be created from invitations.
+func inviteOnly() bool {
+	return strings.EqualFold(os.Getenv("REGISTRATION_MODE"), "invite")
+}
+
+func RegisterInvitationRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager, mail mailer.Mailer) {
+	authRequired := authn.Required()
+	canInvite := auth.Authorize(auth.Has(auth.PermInvitesManage))
+	userCol := db.Collection("users")
+
+	app.Post("/api/invitations", authRequired, canInvite, func(c *fiber.Ctx) error {
+		var req struct {
+			Email   string   `json:"email"`
+			Role    string   `json:"role"`
+			TeamIDs []string `json:"teamIds"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		p := auth.PrincipalFrom(c)
+		email := strings.TrimSpace(req.Email)
+		if !validEmail(email) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid email address"})
+		}
+		if req.Role == "" {
+			req.Role = auth.RoleMember
+		}
+		if !auth.ValidRole(req.Role) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid role"})
+		}
+		if !p.CanGrant(req.Role) {
+			return auth.Forbidden(c)
+		}
+		teamIDs := []primitive.ObjectID{}
+		for _, t := range req.TeamIDs {
+			objID, err := primitive.ObjectIDFromHex(t)
+			if err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid team id"})
+			}
+			teamIDs = append(teamIDs, objID)
+		}
+		ctx := context.Background()
+		tdb := orgDB(c, db)
+		inviteCol := tdb.Collection("invitations")
+		if len(teamIDs) > 0 {
+			count, err := tdb.Collection("teams").CountDocuments(ctx, bson.M{"_id": bson.M{"$in": teamIDs}})
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			if int(count) != len(teamIDs) {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Unknown team id"})
+			}
+		}
+		count, err := userCol.CountDocuments(ctx, bson.M{"email": email})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if count > 0 {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Email already registered"})
+		}
+		token, hash, err := auth.NewSecret()
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		now := time.Now()
+		// Undangan lama untuk email yang sama dibatalkan
+		_, err = inviteCol.UpdateMany(ctx, pendingInvitation(bson.M{"email": email}, now), bson.M{"$set": bson.M{"revokedAt": now}})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		invitation := models.Invitation{
+			ID:        primitive.NewObjectID(),
+			Email:     email,
+			Role:      req.Role,
+			TeamIDs:   teamIDs,
+			TokenHash: hash,
+			InvitedBy: p.UserID,
+			CreatedAt: now,
+			ExpiresAt: now.Add(invitationTTL),
+		}
+		if _, err := inviteCol.InsertOne(ctx, &invitation); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err := sendInvitation(ctx, mail, invitation, p.Name, token); err != nil {
+			log.Printf("Invitation email for %s: %v", invitation.ID.Hex(), err)
+		}
+		return c.Status(http.StatusCreated).JSON(invitation)
+	})
+
+	app.Get("/api/invitations", authRequired, canInvite, func(c *fiber.Ctx) error {
+		ctx := context.Background()
+		cur, err := orgDB(c, db).Collection("invitations").Find(ctx, pendingInvitation(bson.M{}, time.Now()))
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		invitations := []models.Invitation{}
+		if err := cur.All(ctx, &invitations); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(invitations)
+	})
+
+	app.Delete("/api/invitations/:id", authRequired, canInvite, func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid invitation id"})
+		}
+		now := time.Now()
+		res, err := orgDB(c, db).Collection("invitations").UpdateOne(context.Background(), pendingInvitation(bson.M{"_id": id}, now), bson.M{"$set": bson.M{"revokedAt": now}})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if res.MatchedCount == 0 {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Invitation not found"})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	})
+
+	// POST /api/auth/accept-invite - buat akun dari undangan dan masuk ke tim yang dituju
+	app.Post("/api/auth/accept-invite", func(c *fiber.Ctx) error {
+		var req struct {
+			Token    string `json:"token"`
+			Name     string `json:"name"`
+			Password string `json:"password"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" || len(name) > maxNameLength {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Name must be between 1 and 100 characters"})
+		}
+		if len(req.Password) < minPasswordLength {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Password must be at least 8 characters"})
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to hash password"})
+		}
+		ctx := context.Background()
+		now := time.Now()
+		userID := primitive.NewObjectID()
+		// Token berlaku lintas organisasi; setelah ditemukan, semua akses dibatasi ke organisasi undangan
+		inviteCol := db.Collection("invitations")
+		var invitation models.Invitation
+		err = inviteCol.FindOneAndUpdate(ctx,
+			pendingInvitation(bson.M{"tokenHash": auth.HashSecret(req.Token)}, now),
+			bson.M{"$set": bson.M{"acceptedAt": now, "acceptedBy": userID}},
+		).Decode(&invitation)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invitation is invalid or has expired"})
+		}
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		release := func() {
+			_, _ = inviteCol.UpdateOne(ctx, bson.M{"_id": invitation.ID}, bson.M{"$unset": bson.M{"acceptedAt": "", "acceptedBy": ""}})
+		}
+		count, err := userCol.CountDocuments(ctx, bson.M{"email": invitation.Email})
+		if err != nil || count > 0 {
+			release()
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Email already registered"})
+		}
+		// Link undangan dikirim ke email ini, jadi alamatnya sudah terverifikasi
+		user := models.User{
+			ID:              userID,
+			Name:            name,
+			Email:           invitation.Email,
+			Password:        string(hash),
+			Role:            invitation.Role,
+			EmailVerified:   true,
+			EmailVerifiedAt: &now,
+		}
+		tdb := tenant.New(db, invitation.OrgID)
+		if _, err := tdb.Collection("users").InsertOne(ctx, &user); err != nil {
+			release()
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if len(invitation.TeamIDs) > 0 {
+			_, err := tdb.Collection("teams").UpdateMany(ctx, bson.M{"_id": bson.M{"$in": invitation.TeamIDs}}, bson.M{"$addToSet": bson.M{"members": user.ID}})
+			if err != nil {
+				log.Printf("Adding %s to invited teams: %v", user.ID.Hex(), err)
+			}
+		}
+		return c.Status(http.StatusCreated).JSON(userProfile(user))
+	})
+}
+
+// pendingInvitation narrows filter to invitations that can still be
+// accepted.
+func pendingInvitation(filter bson.M, now time.Time) bson.M {
+	filter["acceptedAt"] = bson.M{"$exists": false}
+	filter["revokedAt"] = bson.M{"$exists": false}
+	filter["expiresAt"] = bson.M{"$gt": now}
+	return filter
+}
+
+func sendInvitation(ctx context.Context, mail mailer.Mailer, invitation models.Invitation, inviter, token string) error {
+	link := appURL() + "/accept-invite?token=" + url.QueryEscape(token)
+	return mail.Send(ctx, mailer.Message{
+		To:      invitation.Email,
+		Subject: "You have been invited to Wellbeing Check",
+		Body: inviter + " invited you to join Wellbeing Check.\n\n" +
+			"Open the link below within 7 days to create your account:\n\n" +
+			link + "\n",
+	})
+}
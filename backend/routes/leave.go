@@ -0,0 +1,162 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/auth"
+	"backend/models"
+	"backend/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxLeaveDays    = 90
+	maxReasonLength = 500
+)
+
+// clearAbsences removes absences already recorded for days that turn out
+// to be approved leave.
+func clearAbsences(ctx context.Context, scoped *tenant.DB, leave models.LeaveRequest) error {
+	filter := bson.M{
+		"userId": leave.UserID,
+		"date":   bson.M{"$gte": leave.StartDate, "$lte": leave.EndDate},
+		"status": models.AttendanceAbsent,
+	}
+	ids, err := scoped.Collection("attendance").Distinct(ctx, "checkinId", filter)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		if _, err := scoped.Collection("checkins").DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "status": models.CheckinStatusAbsent}); err != nil {
+			return err
+		}
+	}
+	_, err = scoped.Collection("attendance").DeleteMany(ctx, filter)
+	return err
+}
+
+func RegisterLeaveRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager) {
+	authRequired := authn.Required()
+	canApprove := auth.Authorize(auth.Has(auth.PermLeaveApprove))
+
+	// POST /api/leave - ajukan cuti untuk diri sendiri
+	app.Post("/api/leave", authRequired, func(c *fiber.Ctx) error {
+		var req struct {
+			StartDate string `json:"startDate"`
+			EndDate   string `json:"endDate"`
+			Reason    string `json:"reason"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		start, errStart := time.Parse(time.DateOnly, req.StartDate)
+		end, errEnd := time.Parse(time.DateOnly, req.EndDate)
+		if errStart != nil || errEnd != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Dates must be YYYY-MM-DD"})
+		}
+		if end.Before(start) || end.Sub(start) >= maxLeaveDays*24*time.Hour {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "endDate must be on or after startDate and within 90 days"})
+		}
+		reason := strings.TrimSpace(req.Reason)
+		if len(reason) > maxReasonLength {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Reason must be at most 500 characters"})
+		}
+		leave := models.LeaveRequest{
+			ID:        primitive.NewObjectID(),
+			UserID:    auth.PrincipalFrom(c).UserID,
+			StartDate: req.StartDate,
+			EndDate:   req.EndDate,
+			Reason:    reason,
+			Status:    models.LeavePending,
+			CreatedAt: time.Now(),
+		}
+		if _, err := orgDB(c, db).Collection("leave_requests").InsertOne(context.Background(), &leave); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(http.StatusCreated).JSON(leave)
+	})
+
+	// GET /api/leave?status=&userId= - approver melihat semua, lainnya hanya miliknya
+	app.Get("/api/leave", authRequired, func(c *fiber.Ctx) error {
+		p := auth.PrincipalFrom(c)
+		filter := bson.M{"userId": p.UserID}
+		if p.Can(auth.PermLeaveApprove) {
+			filter = bson.M{}
+			if v := c.Query("userId"); v != "" {
+				id, err := primitive.ObjectIDFromHex(v)
+				if err != nil {
+					return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid userId"})
+				}
+				filter["userId"] = id
+			}
+		}
+		if status := c.Query("status"); status != "" {
+			filter["status"] = status
+		}
+		ctx := context.Background()
+		cur, err := orgDB(c, db).Collection("leave_requests").Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "startDate", Value: -1}}))
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		requests := []models.LeaveRequest{}
+		if err := cur.All(ctx, &requests); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(requests)
+	})
+
+	review := func(status string) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			id, err := primitive.ObjectIDFromHex(c.Params("id"))
+			if err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid leave request id"})
+			}
+			p := auth.PrincipalFrom(c)
+			ctx := context.Background()
+			scoped := orgDB(c, db)
+			now := time.Now()
+			var leave models.LeaveRequest
+			err = scoped.Collection("leave_requests").FindOneAndUpdate(ctx,
+				bson.M{"_id": id, "status": models.LeavePending, "userId": bson.M{"$ne": p.UserID}},
+				bson.M{"$set": bson.M{"status": status, "reviewedBy": p.UserID, "reviewedAt": now}},
+				options.FindOneAndUpdate().SetReturnDocument(options.After),
+			).Decode(&leave)
+			if err != nil {
+				return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "No pending leave request with this id"})
+			}
+			if status == models.LeaveApproved {
+				if err := clearAbsences(ctx, scoped, leave); err != nil {
+					return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+				}
+			}
+			return c.JSON(leave)
+		}
+	}
+	app.Post("/api/leave/:id/approve", authRequired, canApprove, review(models.LeaveApproved))
+	app.Post("/api/leave/:id/reject", authRequired, canApprove, review(models.LeaveRejected))
+
+	// DELETE /api/leave/:id - batalkan pengajuan yang belum diproses
+	app.Delete("/api/leave/:id", authRequired, func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid leave request id"})
+		}
+		res, err := orgDB(c, db).Collection("leave_requests").DeleteOne(context.Background(),
+			bson.M{"_id": id, "userId": auth.PrincipalFrom(c).UserID, "status": models.LeavePending})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if res.DeletedCount == 0 {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "No pending leave request with this id"})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	})
+}
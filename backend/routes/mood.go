@@ -0,0 +1,90 @@
+package routes
+
+import (
+	"context"
+	"strings"
+
+	"backend/auth"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// mood is an entry of the canonical mood vocabulary. Valence runs from -1
+// (unpleasant) to 1 (pleasant) and is stored with each check-in as
+// moodScore so reports can average it.
+type mood struct {
+	Name    string `json:"name"`
+	Valence int    `json:"valence"`
+}
+
+var moods = []mood{
+	{Name: "Happy", Valence: 1},
+	{Name: "Neutral", Valence: 0},
+	{Name: "Stressed", Valence: -1},
+}
+
+// moodAliases maps lower-cased input, including the face-api expressions
+// the check-in page sends, to canonical mood names.
+var moodAliases = map[string]string{
+	"happy":     "Happy",
+	"neutral":   "Neutral",
+	"stressed":  "Stressed",
+	"surprised": "Neutral",
+	"sad":       "Stressed",
+	"angry":     "Stressed",
+	"fearful":   "Stressed",
+	"disgusted": "Stressed",
+}
+
+// faceExpressions are the expressions face-api reports.
+var faceExpressions = map[string]bool{
+	"neutral": true, "happy": true, "sad": true, "angry": true,
+	"fearful": true, "disgusted": true, "surprised": true,
+}
+
+// normalizeMood returns the canonical mood for s. "unknown", which the
+// check-in page sends when no face was detected, counts as no mood.
+func normalizeMood(s string) (m mood, ok bool) {
+	key := strings.ToLower(strings.TrimSpace(s))
+	name, ok := moodAliases[key]
+	if !ok {
+		return mood{}, false
+	}
+	for _, m := range moods {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return mood{}, false
+}
+
+func moodMissing(s string) bool {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return s == "" || s == "unknown"
+}
+
+// NormalizeMoods rewrites moods stored before the vocabulary existed to
+// their canonical names and adds the mood score. Unknown moods are left as
+// they are.
+func NormalizeMoods(ctx context.Context, db *mongo.Database) error {
+	checkins := db.Collection("checkins")
+	for alias := range moodAliases {
+		m, _ := normalizeMood(alias)
+		_, err := checkins.UpdateMany(ctx,
+			bson.M{"mood": bson.M{"$regex": "^" + alias + "$", "$options": "i"}, "moodScore": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"mood": m.Name, "moodScore": m.Valence}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func RegisterMoodRoutes(app *fiber.App, authn *auth.Manager) {
+	// GET /api/moods - daftar mood kanonik beserta skornya
+	app.Get("/api/moods", authn.Required(), func(c *fiber.Ctx) error {
+		return c.JSON(moods)
+	})
+}
@@ -0,0 +1,445 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"backend/auth"
+	"backend/models"
+	"backend/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	minScaleOptions    = 2
+	maxScaleOptions    = 11
+	maxScaleQuestions  = 10
+	maxOptionLabel     = 40
+	maxSliderRange     = 100
+	maxTextAnswerRunes = 500
+)
+
+var (
+	questionKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+	colorPattern       = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+// EnsureMoodScaleIndexes allows a single active mood scale per
+// organization.
+func EnsureMoodScaleIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("mood_scales").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: tenant.Field, Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"active": true}),
+	})
+	return err
+}
+
+// defaultMoodScale describes the canonical moods as a scale, for clients
+// of organizations without one.
+func defaultMoodScale() models.MoodScale {
+	scale := models.MoodScale{Name: "Default", Version: 1, Active: true, Questions: []models.MoodQuestion{}}
+	for _, m := range moods {
+		scale.Options = append(scale.Options, models.MoodOption{Value: m.Valence, Label: m.Name, Mood: m.Name})
+	}
+	return scale
+}
+
+// activeMoodScale returns the organization's active scale, or nil when it
+// uses the canonical moods.
+func activeMoodScale(ctx context.Context, scoped *tenant.DB) (*models.MoodScale, error) {
+	var scale models.MoodScale
+	err := scoped.Collection("mood_scales").FindOne(ctx, bson.M{"active": true}).Decode(&scale)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &scale, nil
+}
+
+// validateMoodScale trims and checks a scale definition.
+func validateMoodScale(s *models.MoodScale) []fieldError {
+	var errs []fieldError
+	s.Name = strings.TrimSpace(s.Name)
+	if s.Name == "" || len(s.Name) > maxNameLength {
+		errs = append(errs, fieldError{"name", "must be between 1 and 100 characters"})
+	}
+	errs = append(errs, validateMoodOptions("options", s.Options, true)...)
+	if len(s.Questions) > maxScaleQuestions {
+		errs = append(errs, fieldError{"questions", fmt.Sprintf("at most %d questions are allowed", maxScaleQuestions)})
+	}
+	seen := map[string]bool{}
+	for i := range s.Questions {
+		q := &s.Questions[i]
+		field := fmt.Sprintf("questions[%d]", i)
+		q.Label = strings.TrimSpace(q.Label)
+		if !questionKeyPattern.MatchString(q.Key) {
+			errs = append(errs, fieldError{field + ".key", "must be lower-case letters, digits or _, starting with a letter"})
+		} else if seen[q.Key] {
+			errs = append(errs, fieldError{field + ".key", "is used by another question"})
+		}
+		seen[q.Key] = true
+		if q.Label == "" || len(q.Label) > maxNameLength {
+			errs = append(errs, fieldError{field + ".label", "must be between 1 and 100 characters"})
+		}
+		switch q.Kind {
+		case models.QuestionSlider:
+			if q.Max <= q.Min || q.Max-q.Min > maxSliderRange {
+				errs = append(errs, fieldError{field + ".max", fmt.Sprintf("must be above min and at most %d above it", maxSliderRange)})
+			}
+			q.Options = nil
+		case models.QuestionChoice:
+			errs = append(errs, validateMoodOptions(field+".options", q.Options, false)...)
+			q.Min, q.Max = 0, 0
+		case models.QuestionText:
+			q.Min, q.Max, q.Options = 0, 0, nil
+		default:
+			errs = append(errs, fieldError{field + ".kind", "must be slider, choice or text"})
+		}
+	}
+	if s.Questions == nil {
+		s.Questions = []models.MoodQuestion{}
+	}
+	return errs
+}
+
+// validateMoodOptions checks the answers of a question. Only the main mood
+// question maps options to canonical moods.
+func validateMoodOptions(field string, opts []models.MoodOption, withMood bool) []fieldError {
+	var errs []fieldError
+	if len(opts) < minScaleOptions || len(opts) > maxScaleOptions {
+		return append(errs, fieldError{field, fmt.Sprintf("must have between %d and %d options", minScaleOptions, maxScaleOptions)})
+	}
+	seen := map[int]bool{}
+	for i := range opts {
+		o := &opts[i]
+		f := fmt.Sprintf("%s[%d]", field, i)
+		o.Label = strings.TrimSpace(o.Label)
+		if seen[o.Value] {
+			errs = append(errs, fieldError{f + ".value", "is used by another option"})
+		}
+		seen[o.Value] = true
+		if o.Label == "" || len(o.Label) > maxOptionLabel {
+			errs = append(errs, fieldError{f + ".label", fmt.Sprintf("must be between 1 and %d characters", maxOptionLabel)})
+		}
+		if len(o.Emoji) > 16 {
+			errs = append(errs, fieldError{f + ".emoji", "is too long"})
+		}
+		if o.Color != "" && !colorPattern.MatchString(o.Color) {
+			errs = append(errs, fieldError{f + ".color", "must be #RRGGBB"})
+		}
+		if !withMood {
+			o.Mood = ""
+		} else if o.Mood != "" {
+			m, ok := normalizeMood(o.Mood)
+			if !ok {
+				errs = append(errs, fieldError{f + ".mood", "must be one of Happy, Neutral, Stressed"})
+			}
+			o.Mood = m.Name
+		}
+	}
+	return errs
+}
+
+// answerMoodScale validates a check-in's answers against scale. option is
+// the chosen answer to the main question, or nil when the check-in has
+// none; required questions are only enforced when it has one.
+func answerMoodScale(scale *models.MoodScale, option *models.MoodOption, answers map[string]any) (*models.MoodAnswer, []fieldError) {
+	var errs []fieldError
+	known := map[string]bool{}
+	for _, q := range scale.Questions {
+		known[q.Key] = true
+	}
+	for key := range answers {
+		if !known[key] {
+			errs = append(errs, fieldError{"answers." + key, "is not a question of the active mood scale"})
+		}
+	}
+	if option == nil {
+		if len(answers) > 0 {
+			errs = append(errs, fieldError{"moodValue", "is required when answering questions"})
+		}
+		return nil, errs
+	}
+	answer := &models.MoodAnswer{
+		ScaleID:      scale.ID,
+		ScaleVersion: scale.Version,
+		Value:        option.Value,
+		Label:        option.Label,
+		Emoji:        option.Emoji,
+		Color:        option.Color,
+	}
+	for _, q := range scale.Questions {
+		field := "answers." + q.Key
+		raw, ok := answers[q.Key]
+		if !ok || raw == nil {
+			if q.Required {
+				errs = append(errs, fieldError{field, "is required"})
+			}
+			continue
+		}
+		qa := models.QuestionAnswer{Key: q.Key, Question: q.Label}
+		switch q.Kind {
+		case models.QuestionText:
+			text, ok := raw.(string)
+			text = strings.TrimSpace(text)
+			if !ok || len([]rune(text)) > maxTextAnswerRunes {
+				errs = append(errs, fieldError{field, fmt.Sprintf("must be text of at most %d characters", maxTextAnswerRunes)})
+				continue
+			}
+			if text == "" {
+				if q.Required {
+					errs = append(errs, fieldError{field, "is required"})
+				}
+				continue
+			}
+			qa.Text = text
+		case models.QuestionSlider:
+			v, ok := integer(raw)
+			if !ok || v < q.Min || v > q.Max {
+				errs = append(errs, fieldError{field, fmt.Sprintf("must be a whole number from %d to %d", q.Min, q.Max)})
+				continue
+			}
+			qa.Value = &v
+		case models.QuestionChoice:
+			v, ok := integer(raw)
+			opt := findMoodOption(q.Options, v)
+			if !ok || opt == nil {
+				errs = append(errs, fieldError{field, "must be the value of one of the question's options"})
+				continue
+			}
+			qa.Value = &v
+			qa.Label = opt.Label
+		}
+		answer.Answers = append(answer.Answers, qa)
+	}
+	return answer, errs
+}
+
+// integer accepts JSON numbers without a fractional part.
+func integer(v any) (int, bool) {
+	f, ok := v.(float64)
+	if !ok || f != math.Trunc(f) || math.Abs(f) > math.MaxInt32 {
+		return 0, false
+	}
+	return int(f), true
+}
+
+func findMoodOption(opts []models.MoodOption, value int) *models.MoodOption {
+	for i := range opts {
+		if opts[i].Value == value {
+			return &opts[i]
+		}
+	}
+	return nil
+}
+
+// moodOptionFor finds the option a free-text mood names, by label or by
+// the canonical mood it maps to.
+func moodOptionFor(scale *models.MoodScale, name string) *models.MoodOption {
+	name = strings.TrimSpace(name)
+	for i, o := range scale.Options {
+		if strings.EqualFold(o.Label, name) {
+			return &scale.Options[i]
+		}
+	}
+	if m, ok := normalizeMood(name); ok {
+		for i, o := range scale.Options {
+			if o.Mood == m.Name {
+				return &scale.Options[i]
+			}
+		}
+	}
+	return nil
+}
+
+func RegisterMoodScaleRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager) {
+	authRequired := authn.Required()
+	canManage := auth.Authorize(auth.Has(auth.PermOrgSettings))
+
+	// findScale loads the :id scale. When it returns nil the error response
+	// has been written.
+	findScale := func(c *fiber.Ctx) (*models.MoodScale, error) {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return nil, c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid mood scale id"})
+		}
+		var scale models.MoodScale
+		err = orgDB(c, db).Collection("mood_scales").FindOne(context.Background(), bson.M{"_id": id}).Decode(&scale)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Mood scale not found"})
+		}
+		if err != nil {
+			return nil, c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return &scale, nil
+	}
+
+	activate := func(ctx context.Context, scoped *tenant.DB, id primitive.ObjectID) error {
+		scales := scoped.Collection("mood_scales")
+		if _, err := scales.UpdateMany(ctx, bson.M{"active": true, "_id": bson.M{"$ne": id}}, bson.M{"$set": bson.M{"active": false}}); err != nil {
+			return err
+		}
+		_, err := scales.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"active": true}})
+		return err
+	}
+
+	// GET /api/mood-scales - semua skala mood organisasi; ?archived=true ikut yang diarsipkan
+	app.Get("/api/mood-scales", authRequired, canManage, func(c *fiber.Ctx) error {
+		filter := bson.M{}
+		if c.Query("archived") != "true" {
+			filter["archived"] = bson.M{"$ne": true}
+		}
+		ctx := context.Background()
+		cur, err := orgDB(c, db).Collection("mood_scales").Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		scales := []models.MoodScale{}
+		if err := cur.All(ctx, &scales); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(scales)
+	})
+
+	// GET /api/mood-scales/active - skala yang dipakai form check-in
+	app.Get("/api/mood-scales/active", authRequired, func(c *fiber.Ctx) error {
+		scale, err := activeMoodScale(context.Background(), orgDB(c, db))
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if scale == nil {
+			return c.JSON(defaultMoodScale())
+		}
+		return c.JSON(scale)
+	})
+
+	app.Get("/api/mood-scales/:id", authRequired, func(c *fiber.Ctx) error {
+		scale, err := findScale(c)
+		if scale == nil {
+			return err
+		}
+		return c.JSON(scale)
+	})
+
+	app.Post("/api/mood-scales", authRequired, canManage, func(c *fiber.Ctx) error {
+		var req models.MoodScale
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errs := validateMoodScale(&req); len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+		now := time.Now()
+		scale := models.MoodScale{
+			ID:        primitive.NewObjectID(),
+			Name:      req.Name,
+			Version:   1,
+			Options:   req.Options,
+			Questions: req.Questions,
+			CreatedBy: auth.PrincipalFrom(c).UserID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		ctx := context.Background()
+		scoped := orgDB(c, db)
+		if _, err := scoped.Collection("mood_scales").InsertOne(ctx, &scale); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if req.Active {
+			if err := activate(ctx, scoped, scale.ID); err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			scale.Active = true
+		}
+		return c.Status(http.StatusCreated).JSON(scale)
+	})
+
+	// PUT /api/mood-scales/:id - ganti definisi skala; check-in lama menyimpan label aslinya
+	app.Put("/api/mood-scales/:id", authRequired, canManage, func(c *fiber.Ctx) error {
+		scale, err := findScale(c)
+		if scale == nil {
+			return err
+		}
+		if scale.Archived {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Archived mood scales cannot be changed"})
+		}
+		var req models.MoodScale
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errs := validateMoodScale(&req); len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+		var updated models.MoodScale
+		err = orgDB(c, db).Collection("mood_scales").FindOneAndUpdate(context.Background(),
+			bson.M{"_id": scale.ID, "version": scale.Version},
+			bson.M{
+				"$set": bson.M{"name": req.Name, "options": req.Options, "questions": req.Questions, "updatedAt": time.Now()},
+				"$inc": bson.M{"version": 1},
+			},
+			options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Mood scale was changed concurrently, reload and try again"})
+		}
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(updated)
+	})
+
+	// POST /api/mood-scales/:id/activate - pakai skala ini untuk check-in berikutnya
+	app.Post("/api/mood-scales/:id/activate", authRequired, canManage, func(c *fiber.Ctx) error {
+		scale, err := findScale(c)
+		if scale == nil {
+			return err
+		}
+		if scale.Archived {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Archived mood scales cannot be activated"})
+		}
+		if err := activate(context.Background(), orgDB(c, db), scale.ID); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		scale.Active = true
+		return c.JSON(scale)
+	})
+
+	// POST /api/mood-scales/:id/deactivate - kembali ke mood bawaan
+	app.Post("/api/mood-scales/:id/deactivate", authRequired, canManage, func(c *fiber.Ctx) error {
+		scale, err := findScale(c)
+		if scale == nil {
+			return err
+		}
+		if _, err := orgDB(c, db).Collection("mood_scales").UpdateOne(context.Background(), bson.M{"_id": scale.ID}, bson.M{"$set": bson.M{"active": false}}); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		scale.Active = false
+		return c.JSON(scale)
+	})
+
+	// DELETE /api/mood-scales/:id - arsipkan; check-in tetap merujuk ke skala ini
+	app.Delete("/api/mood-scales/:id", authRequired, canManage, func(c *fiber.Ctx) error {
+		scale, err := findScale(c)
+		if scale == nil {
+			return err
+		}
+		_, err = orgDB(c, db).Collection("mood_scales").UpdateOne(context.Background(), bson.M{"_id": scale.ID},
+			bson.M{"$set": bson.M{"archived": true, "active": false, "updatedAt": time.Now()}})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	})
+}
@@ -0,0 +1,312 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"backend/auth"
+	"backend/models"
+	"backend/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// defaultOrgSlug names the organization that data from before multi-tenancy
+// is moved into. Registration without an organization also lands there.
+const defaultOrgSlug = "default"
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{0,38}[a-z0-9])?$`)
+
+// orgDB returns the caller's organization-scoped view of db. Handlers behind
+// authRequired use it for every tenant collection.
+func orgDB(c *fiber.Ctx, db *mongo.Database) *tenant.DB {
+	return tenant.New(db, auth.PrincipalFrom(c).OrgID)
+}
+
+// MigrateOrganizations creates the default organization on first start,
+// assigns documents without an organization to it and indexes the tenant
+// field.
+func MigrateOrganizations(ctx context.Context, db *mongo.Database) error {
+	orgCol := db.Collection("organizations")
+	if _, err := orgCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "slug", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return err
+	}
+	var org models.Organization
+	err := orgCol.FindOneAndUpdate(ctx,
+		bson.M{"slug": defaultOrgSlug},
+		bson.M{"$setOnInsert": bson.M{"name": "Default", "settings": models.OrgSettings{}, "createdAt": time.Now()}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&org)
+	if err != nil {
+		return err
+	}
+	for _, name := range tenant.Collections {
+		col := db.Collection(name)
+		if _, err := col.UpdateMany(ctx, bson.M{tenant.Field: bson.M{"$exists": false}}, bson.M{"$set": bson.M{tenant.Field: org.ID}}); err != nil {
+			return err
+		}
+		if _, err := col.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: tenant.Field, Value: 1}}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findOrg looks an organization up by slug, falling back to the default one.
+func findOrg(ctx context.Context, db *mongo.Database, slug string) (*models.Organization, error) {
+	if slug == "" {
+		slug = defaultOrgSlug
+	}
+	var org models.Organization
+	if err := db.Collection("organizations").FindOne(ctx, bson.M{"slug": slug}).Decode(&org); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func RegisterOrganizationRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager) {
+	authRequired := authn.Required()
+	orgCol := db.Collection("organizations")
+
+	// POST /api/orgs - buat organisasi baru beserta org admin pertamanya
+	app.Post("/api/orgs", authRequired, auth.Authorize(auth.Has(auth.PermOrgsCreate)), func(c *fiber.Ctx) error {
+		var req struct {
+			Name     string             `json:"name"`
+			Slug     string             `json:"slug"`
+			Settings models.OrgSettings `json:"settings"`
+			Admin    struct {
+				Name     string `json:"name"`
+				Email    string `json:"email"`
+				Password string `json:"password"`
+			} `json:"admin"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		name := strings.TrimSpace(req.Name)
+		slug := strings.ToLower(strings.TrimSpace(req.Slug))
+		if name == "" || len(name) > maxNameLength {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Name must be between 1 and 100 characters"})
+		}
+		if !slugPattern.MatchString(slug) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Slug must be 1-40 lowercase letters, digits or dashes"})
+		}
+		if err := validateOrgSettings(req.Settings); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		adminName := strings.TrimSpace(req.Admin.Name)
+		adminEmail := strings.TrimSpace(req.Admin.Email)
+		if adminName == "" || !validEmail(adminEmail) || len(req.Admin.Password) < minPasswordLength {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Admin name, valid email and a password of at least 8 characters are required"})
+		}
+		ctx := context.Background()
+		count, err := db.Collection("users").CountDocuments(ctx, bson.M{"email": adminEmail})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if count > 0 {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Email already registered"})
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Admin.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to hash password"})
+		}
+		now := time.Now()
+		org := models.Organization{
+			ID:        primitive.NewObjectID(),
+			Name:      name,
+			Slug:      slug,
+			Settings:  req.Settings,
+			CreatedAt: now,
+		}
+		if _, err := orgCol.InsertOne(ctx, org); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Slug already taken"})
+			}
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		admin := models.User{
+			ID:              primitive.NewObjectID(),
+			Name:            adminName,
+			Email:           adminEmail,
+			Password:        string(hash),
+			Role:            auth.RoleOrgAdmin,
+			EmailVerified:   true,
+			EmailVerifiedAt: &now,
+		}
+		if _, err := tenant.New(db, org.ID).Collection("users").InsertOne(ctx, &admin); err != nil {
+			_, _ = orgCol.DeleteOne(ctx, bson.M{"_id": org.ID})
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(http.StatusCreated).JSON(fiber.Map{"organization": org, "admin": userProfile(admin)})
+	})
+
+	app.Get("/api/org", authRequired, func(c *fiber.Ctx) error {
+		var org models.Organization
+		if err := orgCol.FindOne(context.Background(), bson.M{"_id": auth.PrincipalFrom(c).OrgID}).Decode(&org); err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Organization not found"})
+		}
+		return c.JSON(org)
+	})
+
+	app.Put("/api/org", authRequired, auth.Authorize(auth.Has(auth.PermOrgSettings)), func(c *fiber.Ctx) error {
+		var req struct {
+			Name     *string           `json:"name"`
+			Settings *orgSettingsPatch `json:"settings"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		update := bson.M{}
+		if req.Name != nil {
+			name := strings.TrimSpace(*req.Name)
+			if name == "" || len(name) > maxNameLength {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Name must be between 1 and 100 characters"})
+			}
+			update["name"] = name
+		}
+		if req.Settings != nil {
+			settings := req.Settings.fields()
+			if err := validateOrgSettings(req.Settings.apply(models.OrgSettings{})); err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			}
+			// Hanya setting yang dikirim yang diubah
+			for field, value := range settings {
+				update["settings."+field] = value
+			}
+		}
+		if len(update) == 0 {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "No fields to update"})
+		}
+		var org models.Organization
+		err := orgCol.FindOneAndUpdate(context.Background(), bson.M{"_id": auth.PrincipalFrom(c).OrgID}, bson.M{"$set": update},
+			options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&org)
+		if err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Organization not found"})
+		}
+		return c.JSON(org)
+	})
+}
+
+// orgSettingsPatch is a partial update of OrgSettings; nil fields are left
+// as they are.
+type orgSettingsPatch struct {
+	Timezone            *string         `json:"timezone"`
+	InviteOnly          *bool           `json:"inviteOnly"`
+	MFARequiredRoles    *[]string       `json:"mfaRequiredRoles"`
+	WorkingDays         *[]time.Weekday `json:"workingDays"`
+	AbsenceCutoff       *string         `json:"absenceCutoff"`
+	SelfieRetentionDays *int            `json:"selfieRetentionDays"`
+	AnonymizeFaceData   *bool           `json:"anonymizeFaceData"`
+}
+
+// apply copies the provided fields onto s.
+func (p orgSettingsPatch) apply(s models.OrgSettings) models.OrgSettings {
+	if p.Timezone != nil {
+		s.Timezone = *p.Timezone
+	}
+	if p.InviteOnly != nil {
+		s.InviteOnly = *p.InviteOnly
+	}
+	if p.MFARequiredRoles != nil {
+		s.MFARequiredRoles = *p.MFARequiredRoles
+	}
+	if p.WorkingDays != nil {
+		s.WorkingDays = *p.WorkingDays
+	}
+	if p.AbsenceCutoff != nil {
+		s.AbsenceCutoff = *p.AbsenceCutoff
+	}
+	if p.SelfieRetentionDays != nil {
+		s.SelfieRetentionDays = *p.SelfieRetentionDays
+	}
+	if p.AnonymizeFaceData != nil {
+		s.AnonymizeFaceData = *p.AnonymizeFaceData
+	}
+	return s
+}
+
+// fields returns the provided fields keyed by their bson name.
+func (p orgSettingsPatch) fields() bson.M {
+	set := bson.M{}
+	if p.Timezone != nil {
+		set["timezone"] = *p.Timezone
+	}
+	if p.InviteOnly != nil {
+		set["inviteOnly"] = *p.InviteOnly
+	}
+	if p.MFARequiredRoles != nil {
+		set["mfaRequiredRoles"] = *p.MFARequiredRoles
+	}
+	if p.WorkingDays != nil {
+		set["workingDays"] = *p.WorkingDays
+	}
+	if p.AbsenceCutoff != nil {
+		set["absenceCutoff"] = *p.AbsenceCutoff
+	}
+	if p.SelfieRetentionDays != nil {
+		set["selfieRetentionDays"] = *p.SelfieRetentionDays
+	}
+	if p.AnonymizeFaceData != nil {
+		set["anonymizeFaceData"] = *p.AnonymizeFaceData
+	}
+	return set
+}
+
+func validateOrgSettings(s models.OrgSettings) error {
+	if s.Timezone != "" {
+		if _, err := loadTimezone(s.Timezone); err != nil {
+			return err
+		}
+	}
+	for _, role := range s.MFARequiredRoles {
+		if !auth.ValidRole(role) {
+			return errors.New("Unknown role in mfaRequiredRoles")
+		}
+	}
+	for _, day := range s.WorkingDays {
+		if day < time.Sunday || day > time.Saturday {
+			return errors.New("workingDays must be between 0 (Sunday) and 6 (Saturday)")
+		}
+	}
+	if s.AbsenceCutoff != "" {
+		if _, err := parseClock(s.AbsenceCutoff); err != nil {
+			return errors.New("absenceCutoff must be HH:MM")
+		}
+	}
+	if s.SelfieRetentionDays < 0 || s.SelfieRetentionDays > maxSelfieRetentionDays {
+		return errors.New("selfieRetentionDays must be between 0 and 3650")
+	}
+	return nil
+}
+
+// mfaRequired reports whether the user's organization requires two-factor
+// authentication for their role.
+func mfaRequired(ctx context.Context, db *mongo.Database, user models.User) (bool, error) {
+	var org models.Organization
+	err := db.Collection("organizations").FindOne(ctx, bson.M{"_id": user.OrgID}).Decode(&org)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, role := range org.Settings.MFARequiredRoles {
+		if role == user.Role {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,105 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"backend/auth"
+	"backend/mailer"
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const passwordResetTTL = time.Hour
+
+// appURL is the frontend base URL used in links sent by email.
+func appURL() string {
+	if v := os.Getenv("APP_URL"); v != "" {
+		return strings.TrimRight(v, "/")
+	}
+	return "http://localhost:3000"
+}
+
+func RegisterPasswordRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager, mail mailer.Mailer) {
+	userCol := db.Collection("users")
+
+	// POST /api/auth/forgot-password - selalu 200 agar email terdaftar tidak bisa ditebak
+	app.Post("/api/auth/forgot-password", func(c *fiber.Ctx) error {
+		var req struct {
+			Email string `json:"email"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		ctx := context.Background()
+		var user models.User
+		err := userCol.FindOne(ctx, bson.M{"email": strings.TrimSpace(req.Email)}).Decode(&user)
+		if err == nil {
+			if err := sendPasswordReset(ctx, authn, mail, user); err != nil {
+				log.Printf("Password reset for %s: %v", user.ID.Hex(), err)
+			}
+		} else if !errors.Is(err, mongo.ErrNoDocuments) {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"success": true, "message": "If the email is registered, a reset link has been sent"})
+	})
+
+	app.Post("/api/auth/reset-password", func(c *fiber.Ctx) error {
+		var req struct {
+			Token    string `json:"token"`
+			Password string `json:"password"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if len(req.Password) < minPasswordLength {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Password must be at least 8 characters"})
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to hash password"})
+		}
+		ctx := context.Background()
+		userID, err := authn.ConsumeUserToken(ctx, req.Token, auth.PurposePasswordReset)
+		if errors.Is(err, auth.ErrInvalidUserToken) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Reset link is invalid or has expired"})
+		}
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if _, err := userCol.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{"password": string(hash)}, "$unset": bson.M{"passwordResetRequired": ""}}); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if _, err := authn.RevokeAllSessions(ctx, userID, primitive.NilObjectID); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	})
+}
+
+func sendPasswordReset(ctx context.Context, authn *auth.Manager, mail mailer.Mailer, user models.User) error {
+	token, err := authn.IssueUserToken(ctx, user.ID, auth.PurposePasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	link := appURL() + "/reset-password?token=" + url.QueryEscape(token)
+	return mail.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Wellbeing Check password",
+		Body: "Hi " + user.Name + ",\n\n" +
+			"Someone asked to reset the password for your account. Open the link below within one hour to choose a new one:\n\n" +
+			link + "\n\n" +
+			"If you did not ask for this, you can ignore this email.\n",
+	})
+}
@@ -3,45 +3,24 @@ package routes
 import (
 	"context"
 	"net/http"
-	"os"
 	"time"
 
+	"backend/auth"
 	"backend/models"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func RegisterProjectRoutes(app *fiber.App, db *mongo.Database) {
-	jwtSecret := []byte(os.Getenv("JWT_SECRET"))
-	authRequired := func(c *fiber.Ctx) error {
-		tokenStr := c.Get("Authorization")
-		if tokenStr == "" || len(tokenStr) < 8 {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Missing or invalid token"})
-		}
-		tokenStr = tokenStr[7:]
-		token, err := jwt.ParseWithClaims(tokenStr, jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
-		if err != nil || !token.Valid {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
-		}
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
-		}
-		c.Locals("userId", claims["id"])
-		c.Locals("userRole", claims["role"])
-		return c.Next()
-	}
-
-	projectCol := db.Collection("projects")
+func RegisterProjectRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager) {
+	authRequired := authn.Required()
 
 	app.Get("/api/projects", authRequired, func(c *fiber.Ctx) error {
 		ctx := context.Background()
+		tdb := orgDB(c, db)
+		projectCol := tdb.Collection("projects")
 		cur, err := projectCol.Find(ctx, bson.M{})
 		if err != nil {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
@@ -51,8 +30,8 @@ func RegisterProjectRoutes(app *fiber.App, db *mongo.Database) {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
 
-		teamCol := db.Collection("teams")
-		userCol := db.Collection("users")
+		teamCol := tdb.Collection("teams")
+		userCol := tdb.Collection("users")
 
 		var result []fiber.Map
 		for _, p := range projects {
@@ -115,7 +94,7 @@ func RegisterProjectRoutes(app *fiber.App, db *mongo.Database) {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project id"})
 		}
 		var project models.Project
-		err = projectCol.FindOne(context.Background(), bson.M{"_id": id}).Decode(&project)
+		err = orgDB(c, db).Collection("projects").FindOne(context.Background(), bson.M{"_id": id}).Decode(&project)
 		if err != nil {
 			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Project not found"})
 		}
@@ -123,7 +102,7 @@ func RegisterProjectRoutes(app *fiber.App, db *mongo.Database) {
 	})
 
 	// POST /api/projects
-	app.Post("/api/projects", authRequired, func(c *fiber.Ctx) error {
+	app.Post("/api/projects", authRequired, auth.Authorize(auth.Has(auth.PermProjectsCreate)), func(c *fiber.Ctx) error {
 		var req struct {
 			Name        string   `json:"name"`
 			Description string   `json:"description"`
@@ -163,7 +142,7 @@ func RegisterProjectRoutes(app *fiber.App, db *mongo.Database) {
 			Teams:       teamObjIDs,
 			CreatedAt:   time.Now(),
 		}
-		_, err := projectCol.InsertOne(context.Background(), project)
+		_, err := orgDB(c, db).Collection("projects").InsertOne(context.Background(), &project)
 		if err != nil {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
@@ -171,7 +150,7 @@ func RegisterProjectRoutes(app *fiber.App, db *mongo.Database) {
 	})
 
 	// PUT /api/projects/:id
-	app.Put("/api/projects/:id", authRequired, func(c *fiber.Ctx) error {
+	app.Put("/api/projects/:id", authRequired, auth.Authorize(auth.Has(auth.PermProjectsUpdate)), func(c *fiber.Ctx) error {
 		id, err := primitive.ObjectIDFromHex(c.Params("id"))
 		if err != nil {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project id"})
@@ -212,19 +191,19 @@ func RegisterProjectRoutes(app *fiber.App, db *mongo.Database) {
 			}
 			update["teams"] = teamObjIDs
 		}
-		_, err = projectCol.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": update})
+		_, err = orgDB(c, db).Collection("projects").UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": update})
 		if err != nil {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.JSON(fiber.Map{"success": true})
 	})
 
-	app.Delete("/api/projects/:id", authRequired, func(c *fiber.Ctx) error {
+	app.Delete("/api/projects/:id", authRequired, auth.Authorize(auth.Has(auth.PermProjectsDelete)), func(c *fiber.Ctx) error {
 		id, err := primitive.ObjectIDFromHex(c.Params("id"))
 		if err != nil {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid project id"})
 		}
-		_, err = projectCol.DeleteOne(context.Background(), bson.M{"_id": id})
+		_, err = orgDB(c, db).Collection("projects").DeleteOne(context.Background(), bson.M{"_id": id})
 		if err != nil {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
@@ -234,9 +213,7 @@ func RegisterProjectRoutes(app *fiber.App, db *mongo.Database) {
 	// GET /api/projects-with-team
 	app.Get("/api/projects-with-team", authRequired, func(c *fiber.Ctx) error {
 		ctx := context.Background()
-		projectCol := db.Collection("projects")
-
-		cur, err := projectCol.Find(ctx, bson.M{})
+		cur, err := orgDB(c, db).Collection("projects").Find(ctx, bson.M{})
 		if err != nil {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
@@ -0,0 +1,317 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"backend/auth"
+	"backend/models"
+	"backend/storage"
+	"backend/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxSelfieRetentionDays = 3650
+	// maxPurgeReportIDs caps the check-in ids listed in one purge report.
+	maxPurgeReportIDs = 1000
+)
+
+// selfiePurgeJob deletes check-in selfies older than each organization's
+// SelfieRetentionDays and writes a report per organization and run.
+type selfiePurgeJob struct {
+	db       *mongo.Database
+	store    storage.BlobStore
+	interval time.Duration
+	dryRun   bool
+}
+
+// StartSelfiePurgeJob runs the selfie retention purger in the background
+// until ctx ends. SELFIE_PURGE_INTERVAL (default 1h, 0 disables) sets how
+// often it runs; SELFIE_PURGE_DRY_RUN=true makes it only report what it
+// would remove.
+func StartSelfiePurgeJob(ctx context.Context, db *mongo.Database, store storage.BlobStore) error {
+	job := &selfiePurgeJob{db: db, store: store, interval: time.Hour}
+	if v := os.Getenv("SELFIE_PURGE_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		job.interval = d
+	}
+	if job.interval <= 0 {
+		log.Println("Selfie purge job disabled")
+		return nil
+	}
+	if v := os.Getenv("SELFIE_PURGE_DRY_RUN"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			return errors.New("SELFIE_PURGE_DRY_RUN must be true or false")
+		}
+		job.dryRun = dryRun
+	}
+	go func() {
+		ticker := time.NewTicker(job.interval)
+		defer ticker.Stop()
+		for {
+			job.run(ctx, time.Now())
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func (j *selfiePurgeJob) run(ctx context.Context, now time.Time) {
+	cur, err := j.db.Collection("organizations").Find(ctx, bson.M{"settings.selfieRetentionDays": bson.M{"$gt": 0}})
+	if err != nil {
+		log.Printf("Selfie purge job: %v", err)
+		return
+	}
+	var orgs []models.Organization
+	if err := cur.All(ctx, &orgs); err != nil {
+		log.Printf("Selfie purge job: %v", err)
+		return
+	}
+	for _, org := range orgs {
+		report, err := purgeSelfies(ctx, j.db, j.store, org, org.Settings.SelfieRetentionDays, j.dryRun, nil, now)
+		if err != nil {
+			log.Printf("Selfie purge job for org %s: %v", org.Slug, err)
+			continue
+		}
+		if n := report.SelfiesDeleted + report.InlineSelfiesCleared + report.FaceResultsAnonymized; n > 0 || report.Failed > 0 {
+			log.Printf("Selfie purge job for org %s (dry run: %v): %d selfies, %d inline selfies, %d face results, %d failed",
+				org.Slug, report.DryRun, report.SelfiesDeleted, report.InlineSelfiesCleared, report.FaceResultsAnonymized, report.Failed)
+		}
+	}
+}
+
+// purgeSelfies removes the selfies of org's check-ins older than
+// retentionDays: uploaded selfies are deleted from the blob store, legacy
+// inline selfies are cleared, and with AnonymizeFaceData the estimated age
+// and gender are dropped. The report is stored in selfie_purges, also when
+// the run stops on an error.
+func purgeSelfies(ctx context.Context, db *mongo.Database, store storage.BlobStore, org models.Organization, retentionDays int, dryRun bool, triggeredBy *primitive.ObjectID, now time.Time) (*models.SelfiePurge, error) {
+	scoped := tenant.New(db, org.ID)
+	report := &models.SelfiePurge{
+		ID:            primitive.NewObjectID(),
+		DryRun:        dryRun,
+		TriggeredBy:   triggeredBy,
+		RetentionDays: retentionDays,
+		Cutoff:        now.AddDate(0, 0, -retentionDays),
+		CheckinIDs:    []primitive.ObjectID{},
+		StartedAt:     time.Now(),
+	}
+	err := purgeOrgSelfies(ctx, scoped, store, org.Settings.AnonymizeFaceData, report)
+	if err != nil {
+		report.Error = err.Error()
+	}
+	report.FinishedAt = time.Now()
+	if _, insertErr := scoped.Collection("selfie_purges").InsertOne(ctx, report); insertErr != nil && err == nil {
+		err = insertErr
+	}
+	return report, err
+}
+
+func purgeOrgSelfies(ctx context.Context, scoped *tenant.DB, store storage.BlobStore, anonymize bool, report *models.SelfiePurge) error {
+	note := func(id primitive.ObjectID) {
+		if len(report.CheckinIDs) < maxPurgeReportIDs {
+			report.CheckinIDs = append(report.CheckinIDs, id)
+		} else {
+			report.Truncated = true
+		}
+	}
+	checkins := scoped.Collection("checkins")
+	uploads := scoped.Collection("uploads")
+	purgedAt := time.Now()
+
+	// Selfie di blob store, termasuk upload yang tidak pernah dipakai
+	cur, err := uploads.Find(ctx, bson.M{"createdAt": bson.M{"$lt": report.Cutoff}})
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		var up models.Upload
+		if err := cur.Decode(&up); err != nil {
+			return err
+		}
+		if !report.DryRun {
+			if err := deleteUpload(ctx, scoped, store, up, purgedAt); err != nil {
+				// Satu blob yang gagal tidak menghentikan purge
+				log.Printf("Purging upload %s: %v", up.ID.Hex(), err)
+				report.Failed++
+				continue
+			}
+		}
+		report.SelfiesDeleted++
+		report.BytesDeleted += int64(up.Size)
+		if up.CheckinID != nil {
+			note(*up.CheckinID)
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+
+	// Selfie base64 lama yang belum dimigrasi
+	inline := bson.M{"createdAt": bson.M{"$lt": report.Cutoff}, "selfieUrl": bson.M{"$regex": "^data:"}}
+	var listed []models.Checkin
+	cur, err = checkins.Find(ctx, inline, options.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetLimit(int64(maxPurgeReportIDs-len(report.CheckinIDs)+1)))
+	if err != nil {
+		return err
+	}
+	if err := cur.All(ctx, &listed); err != nil {
+		return err
+	}
+	for _, checkin := range listed {
+		note(checkin.ID)
+	}
+	if report.DryRun {
+		report.InlineSelfiesCleared, err = checkins.CountDocuments(ctx, inline)
+		if err != nil {
+			return err
+		}
+	} else {
+		res, err := checkins.UpdateMany(ctx, inline, bson.M{"$set": bson.M{"selfieUrl": "", "selfiePurgedAt": purgedAt}})
+		if err != nil {
+			return err
+		}
+		report.InlineSelfiesCleared = res.ModifiedCount
+	}
+
+	if !anonymize {
+		return nil
+	}
+	faces := bson.M{
+		"createdAt": bson.M{"$lt": report.Cutoff},
+		"$or": bson.A{
+			bson.M{"faceResult.gender": bson.M{"$exists": true}},
+			bson.M{"faceResult.age": bson.M{"$exists": true}},
+		},
+	}
+	if report.DryRun {
+		report.FaceResultsAnonymized, err = checkins.CountDocuments(ctx, faces)
+		return err
+	}
+	res, err := checkins.UpdateMany(ctx, faces, bson.M{"$unset": bson.M{"faceResult.gender": "", "faceResult.age": ""}})
+	if err != nil {
+		return err
+	}
+	report.FaceResultsAnonymized = res.ModifiedCount
+	return nil
+}
+
+// deleteUpload removes an upload's blobs, then its record and the
+// check-in's reference to it.
+func deleteUpload(ctx context.Context, scoped *tenant.DB, store storage.BlobStore, up models.Upload, purgedAt time.Time) error {
+	if err := store.Delete(ctx, up.Key); err != nil {
+		return err
+	}
+	if up.ThumbnailKey != "" {
+		if err := store.Delete(ctx, up.ThumbnailKey); err != nil {
+			return err
+		}
+	}
+	if up.CheckinID != nil {
+		_, err := scoped.Collection("checkins").UpdateOne(ctx, bson.M{"_id": *up.CheckinID, "selfieId": up.ID}, bson.M{
+			"$unset": bson.M{"selfieId": "", "selfieKey": "", "selfieThumbKey": ""},
+			"$set":   bson.M{"selfiePurgedAt": purgedAt},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	_, err := scoped.Collection("uploads").DeleteOne(ctx, bson.M{"_id": up.ID})
+	return err
+}
+
+func RegisterRetentionRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager, store storage.BlobStore) {
+	authRequired := authn.Required()
+	canManage := auth.Authorize(auth.Has(auth.PermOrgSettings))
+
+	// GET /api/org/selfie-purges - laporan purge selfie, terbaru dulu
+	app.Get("/api/org/selfie-purges", authRequired, canManage, func(c *fiber.Ctx) error {
+		page, limit := pageParams(c)
+		ctx := context.Background()
+		purges := orgDB(c, db).Collection("selfie_purges")
+		filter := bson.M{}
+		if v := c.Query("dryRun"); v != "" {
+			dryRun, err := strconv.ParseBool(v)
+			if err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "dryRun must be true or false"})
+			}
+			filter["dryRun"] = dryRun
+		}
+		total, err := purges.CountDocuments(ctx, filter)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		cur, err := purges.Find(ctx, filter, options.Find().
+			SetSort(bson.D{{Key: "startedAt", Value: -1}}).
+			SetSkip((page-1)*limit).SetLimit(limit))
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		data := []models.SelfiePurge{}
+		if err := cur.All(ctx, &data); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"data": data, "total": total, "page": page, "limit": limit})
+	})
+
+	// POST /api/org/selfie-purges - jalankan purge sekarang
+	// Body: {dryRun, retentionDays}; retentionDays hanya untuk dry run, untuk
+	// melihat dampak kebijakan sebelum disimpan
+	app.Post("/api/org/selfie-purges", authRequired, canManage, func(c *fiber.Ctx) error {
+		var req struct {
+			DryRun        bool `json:"dryRun"`
+			RetentionDays *int `json:"retentionDays"`
+		}
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			}
+		}
+		p := auth.PrincipalFrom(c)
+		ctx := context.Background()
+		var org models.Organization
+		if err := db.Collection("organizations").FindOne(ctx, bson.M{"_id": p.OrgID}).Decode(&org); err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Organization not found"})
+		}
+		days := org.Settings.SelfieRetentionDays
+		if req.RetentionDays != nil {
+			if !req.DryRun {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "retentionDays can only be overridden for a dry run"})
+			}
+			days = *req.RetentionDays
+			if days <= 0 || days > maxSelfieRetentionDays {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "retentionDays must be between 1 and 3650"})
+			}
+		}
+		if days <= 0 {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "No selfie retention period is configured", "code": "retention_not_configured"})
+		}
+		report, err := purgeSelfies(ctx, db, store, org, days, req.DryRun, &p.UserID, time.Now())
+		if err != nil {
+			log.Printf("Selfie purge for org %s: %v", org.Slug, err)
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Purge failed", "report": report})
+		}
+		return c.Status(http.StatusCreated).JSON(report)
+	})
+}
@@ -0,0 +1,159 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"backend/models"
+	"backend/storage"
+	"backend/tenant"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SelfieMigrationOptions controls MigrateInlineSelfies.
+type SelfieMigrationOptions struct {
+	BatchSize   int64
+	Limit       int64 // 0 = semua
+	DryRun      bool
+	RetryFailed bool
+	// Progress, when set, is called after every batch.
+	Progress func(SelfieMigrationReport)
+}
+
+// SelfieMigrationReport counts what a migration run did.
+type SelfieMigrationReport struct {
+	Scanned    int64
+	Migrated   int64
+	Failed     int64
+	Skipped    int64 // changed by someone else while being migrated
+	BytesMoved int64
+	Failures   []SelfieMigrationFailure
+}
+
+type SelfieMigrationFailure struct {
+	CheckinID primitive.ObjectID
+	Reason    string
+}
+
+// inlineSelfieFilter matches check-ins whose selfie is still an inline
+// data URI.
+func inlineSelfieFilter(retryFailed bool) bson.M {
+	filter := bson.M{"selfieUrl": bson.M{"$regex": "^data:"}, "selfieKey": bson.M{"$exists": false}}
+	if !retryFailed {
+		filter["selfieMigrationError"] = bson.M{"$exists": false}
+	}
+	return filter
+}
+
+// MigrateInlineSelfies moves base64 selfies stored in check-ins into the
+// blob store. It pages through matching check-ins by id rather than
+// holding one long cursor, and rewrites each check-in only if its selfie
+// is unchanged, so it can run while the API serves traffic. Migrated
+// check-ins stop matching, which makes the migration resumable; ones that
+// cannot be decoded are marked with selfieMigrationError and skipped by
+// later runs unless RetryFailed is set.
+func MigrateInlineSelfies(ctx context.Context, db *mongo.Database, store storage.BlobStore, opts SelfieMigrationOptions) (SelfieMigrationReport, error) {
+	var report SelfieMigrationReport
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 50
+	}
+	checkins := db.Collection("checkins")
+	lastID := primitive.NilObjectID
+	for opts.Limit == 0 || report.Scanned < opts.Limit {
+		batch := opts.BatchSize
+		if opts.Limit > 0 && opts.Limit-report.Scanned < batch {
+			batch = opts.Limit - report.Scanned
+		}
+		filter := inlineSelfieFilter(opts.RetryFailed)
+		filter["_id"] = bson.M{"$gt": lastID}
+		cur, err := checkins.Find(ctx, filter, options.Find().
+			SetSort(bson.D{{Key: "_id", Value: 1}}).
+			SetLimit(batch).
+			SetProjection(bson.M{"_id": 1, "orgId": 1, "userId": 1, "selfieUrl": 1, "createdAt": 1}))
+		if err != nil {
+			return report, err
+		}
+		var docs []models.Checkin
+		if err := cur.All(ctx, &docs); err != nil {
+			return report, err
+		}
+		if len(docs) == 0 {
+			break
+		}
+		for _, doc := range docs {
+			lastID = doc.ID
+			report.Scanned++
+			if err := migrateSelfie(ctx, db, store, doc, opts.DryRun, &report); err != nil {
+				return report, err
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(report)
+		}
+	}
+	return report, nil
+}
+
+// migrateSelfie moves one check-in's selfie. Only storage and database
+// errors are returned; bad images are counted as failures.
+func migrateSelfie(ctx context.Context, db *mongo.Database, store storage.BlobStore, doc models.Checkin, dryRun bool, report *SelfieMigrationReport) error {
+	checkins := db.Collection("checkins")
+	fail := func(reason string) error {
+		report.Failed++
+		report.Failures = append(report.Failures, SelfieMigrationFailure{CheckinID: doc.ID, Reason: reason})
+		if dryRun {
+			return nil
+		}
+		_, err := checkins.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{"selfieMigrationError": reason}})
+		return err
+	}
+	if doc.OrgID.IsZero() {
+		return fail("check-in has no organization")
+	}
+	data, err := decodeDataURL(doc.SelfieURL)
+	if err != nil {
+		return fail("not a base64 data URI")
+	}
+	if dryRun {
+		if _, _, err := inspectImage(data); err != nil {
+			return fail(err.Error())
+		}
+		report.Migrated++
+		report.BytesMoved += int64(len(doc.SelfieURL))
+		return nil
+	}
+	scoped := tenant.New(db, doc.OrgID)
+	// Tanggal upload mengikuti check-in agar masa retensi tidak mulai ulang
+	up, err := storeSelfie(ctx, store, scoped, doc.UserID, data, doc.CreatedAt)
+	if errors.Is(err, errInvalidImage) {
+		return fail(err.Error())
+	}
+	if err != nil {
+		return err
+	}
+	res, err := checkins.UpdateOne(ctx,
+		bson.M{"_id": doc.ID, "selfieUrl": doc.SelfieURL, "selfieKey": bson.M{"$exists": false}},
+		bson.M{
+			"$set":   bson.M{"selfieUrl": "", "selfieId": up.ID, "selfieKey": up.Key, "selfieThumbKey": up.ThumbnailKey, "selfieMigratedAt": time.Now()},
+			"$unset": bson.M{"selfieMigrationError": ""},
+		})
+	if err == nil && res.MatchedCount == 0 {
+		report.Skipped++
+	}
+	if err != nil || res.MatchedCount == 0 {
+		// Dokumen berubah atau update gagal: buang blob yang baru ditulis
+		_ = discardUpload(ctx, scoped, store, *up)
+		return err
+	}
+	if _, err := scoped.Collection("uploads").UpdateOne(ctx, bson.M{"_id": up.ID}, bson.M{"$set": bson.M{"checkinId": doc.ID}}); err != nil {
+		return err
+	}
+	report.Migrated++
+	report.BytesMoved += int64(len(doc.SelfieURL))
+	return nil
+}
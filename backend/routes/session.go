@@ -0,0 +1,89 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"backend/auth"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func RegisterSessionRoutes(app *fiber.App, authn *auth.Manager) {
+	authRequired := authn.Required()
+
+	// POST /api/auth/refresh - tukar refresh token dengan pasangan token baru
+	app.Post("/api/auth/refresh", func(c *fiber.Ctx) error {
+		var req struct {
+			RefreshToken string `json:"refreshToken"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		tokens, user, err := authn.Refresh(context.Background(), req.RefreshToken, c.Get(fiber.HeaderUserAgent), c.IP())
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid refresh token"})
+		}
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"token": tokens.AccessToken, "refreshToken": tokens.RefreshToken, "user": fiber.Map{"id": user.ID.Hex(), "name": user.Name, "email": user.Email, "role": user.Role}})
+	})
+
+	app.Post("/api/auth/logout", authn.RequiredAllowEnrollment(), func(c *fiber.Ctx) error {
+		p := auth.PrincipalFrom(c)
+		if _, err := authn.RevokeSession(context.Background(), p.UserID, p.SessionID); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	})
+
+	// POST /api/auth/logout-all - keluar dari semua perangkat, termasuk yang ini
+	app.Post("/api/auth/logout-all", authRequired, func(c *fiber.Ctx) error {
+		p := auth.PrincipalFrom(c)
+		revoked, err := authn.RevokeAllSessions(context.Background(), p.UserID, primitive.NilObjectID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"success": true, "revoked": revoked})
+	})
+
+	app.Get("/api/auth/sessions", authRequired, func(c *fiber.Ctx) error {
+		p := auth.PrincipalFrom(c)
+		sessions, err := authn.ListSessions(context.Background(), p.UserID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		result := []fiber.Map{}
+		for _, s := range sessions {
+			result = append(result, fiber.Map{
+				"id":         s.ID.Hex(),
+				"device":     s.Device,
+				"ip":         s.IP,
+				"createdAt":  s.CreatedAt,
+				"lastSeenAt": s.LastSeenAt,
+				"expiresAt":  s.ExpiresAt,
+				"current":    s.ID == p.SessionID,
+			})
+		}
+		return c.JSON(result)
+	})
+
+	app.Delete("/api/auth/sessions/:id", authRequired, func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid session id"})
+		}
+		p := auth.PrincipalFrom(c)
+		revoked, err := authn.RevokeSession(context.Background(), p.UserID, id)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !revoked {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Session not found"})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	})
+}
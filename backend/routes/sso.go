@@ -0,0 +1,249 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/auth"
+	"backend/models"
+	"backend/oidc"
+	"backend/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ssoStateTTL bounds how long the user may spend at the identity provider.
+const ssoStateTTL = 10 * time.Minute
+
+// SSORedirectURL is the frontend page the identity provider sends the
+// browser back to. It posts the code and state to /api/auth/oidc/callback.
+func SSORedirectURL() string {
+	return appURL() + "/auth/oidc/callback"
+}
+
+// EnsureSSOIndexes expires abandoned single sign-on attempts.
+func EnsureSSOIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("sso_states").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// RegisterSSORoutes adds OpenID Connect login. provider is nil when single
+// sign-on is not configured; the routes then answer 404.
+func RegisterSSORoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager, provider *oidc.Provider) {
+	states := db.Collection("sso_states")
+
+	app.Get("/api/auth/oidc", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"enabled": provider != nil})
+	})
+
+	// GET /api/auth/oidc/login - URL tujuan redirect ke identity provider
+	app.Get("/api/auth/oidc/login", func(c *fiber.Ctx) error {
+		if provider == nil {
+			return ssoDisabled(c)
+		}
+		state, stateHash, err := auth.NewSecret()
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		nonce, _, err := auth.NewSecret()
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		verifier, _, err := auth.NewSecret()
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		ctx := context.Background()
+		url, err := provider.AuthCodeURL(ctx, state, nonce, oidc.PKCEChallenge(verifier))
+		if err != nil {
+			log.Printf("OIDC: %v", err)
+			return c.Status(http.StatusBadGateway).JSON(fiber.Map{"error": "Identity provider is unavailable"})
+		}
+		now := time.Now()
+		_, err = states.InsertOne(ctx, models.SSOState{
+			StateHash:    stateHash,
+			Nonce:        nonce,
+			CodeVerifier: verifier,
+			CreatedAt:    now,
+			ExpiresAt:    now.Add(ssoStateTTL),
+		})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"authorizationUrl": url})
+	})
+
+	// POST /api/auth/oidc/callback - tukar code dari identity provider dengan sesi
+	app.Post("/api/auth/oidc/callback", func(c *fiber.Ctx) error {
+		if provider == nil {
+			return ssoDisabled(c)
+		}
+		var req struct {
+			Code  string `json:"code"`
+			State string `json:"state"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if req.Code == "" || req.State == "" {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Code and state are required"})
+		}
+		ctx := context.Background()
+		var st models.SSOState
+		err := states.FindOneAndDelete(ctx, bson.M{"_id": auth.HashSecret(req.State), "expiresAt": bson.M{"$gt": time.Now()}}).Decode(&st)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Sign-in attempt is invalid or has expired", "code": "invalid_state"})
+		}
+		rawIDToken, err := provider.Exchange(ctx, req.Code, st.CodeVerifier)
+		if err != nil {
+			log.Printf("OIDC code exchange: %v", err)
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Single sign-on failed"})
+		}
+		identity, err := provider.Verify(ctx, rawIDToken, st.Nonce)
+		if err != nil {
+			log.Printf("OIDC: %v", err)
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Single sign-on failed"})
+		}
+		user, status, err := ssoUser(ctx, db, provider, identity)
+		if err != nil {
+			if status == http.StatusInternalServerError {
+				log.Printf("OIDC provisioning: %v", err)
+			}
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if user.Deactivated {
+			return accountDeactivated(c)
+		}
+		if user.TOTPEnabled {
+			challenge, err := authn.IssueChallenge(*user)
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
+			}
+			return c.JSON(fiber.Map{"mfaRequired": true, "challengeToken": challenge})
+		}
+		return startLoginSession(c, db, authn, *user)
+	})
+}
+
+// ssoUser finds the account for identity in the provider's organization,
+// linking an existing account by verified email on first use or
+// provisioning a new one. Accounts of other organizations are never
+// linked. Group-mapped roles are applied on every login. The returned
+// status goes with the error.
+func ssoUser(ctx context.Context, db *mongo.Database, provider *oidc.Provider, identity *oidc.Identity) (*models.User, int, error) {
+	role := provider.RoleFor(identity.Groups)
+	if role != "" && !auth.ValidRole(role) {
+		return nil, http.StatusInternalServerError, errors.New("OIDC role mapping names unknown role " + role)
+	}
+	org, err := findOrg(ctx, db, provider.Organization)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("Organization for single sign-on not found")
+	}
+	users := tenant.New(db, org.ID).Collection("users")
+
+	var user models.User
+	err = users.FindOne(ctx, bson.M{"oidcSubject": identity.Subject}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if identity.Email == "" || !identity.EmailVerified {
+			return nil, http.StatusForbidden, errors.New("Identity provider did not supply a verified email address")
+		}
+		email := strings.TrimSpace(identity.Email)
+		// Identitas atau email yang sudah dipakai di organisasi lain tidak
+		// boleh dihubungkan ke organisasi provider ini
+		elsewhere, err := db.Collection("users").CountDocuments(ctx, bson.M{
+			tenant.Field: bson.M{"$ne": org.ID},
+			"$or":        bson.A{bson.M{"oidcSubject": identity.Subject}, bson.M{"email": email}},
+		})
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		if elsewhere > 0 {
+			return nil, http.StatusConflict, errors.New("Account belongs to a different organization")
+		}
+		err = users.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+		switch {
+		case err == nil:
+			if user.ServiceAccount || (user.OIDCSubject != "" && user.OIDCSubject != identity.Subject) {
+				return nil, http.StatusConflict, errors.New("Account is linked to a different identity")
+			}
+			// Hubungkan akun lama dengan identitas SSO
+			now := time.Now()
+			set := bson.M{"oidcSubject": identity.Subject}
+			if !user.EmailVerified {
+				set["emailVerified"] = true
+				set["emailVerifiedAt"] = now
+				user.EmailVerified, user.EmailVerifiedAt = true, &now
+			}
+			if _, err := users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": set}); err != nil {
+				return nil, http.StatusInternalServerError, err
+			}
+			user.OIDCSubject = identity.Subject
+		case errors.Is(err, mongo.ErrNoDocuments):
+			return provisionSSOUser(ctx, users, provider, identity, email, role)
+		default:
+			return nil, http.StatusInternalServerError, err
+		}
+	} else if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	if role != "" && role != user.Role {
+		if _, err := users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"role": role}}); err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		user.Role = role
+	}
+	return &user, http.StatusOK, nil
+}
+
+// provisionSSOUser creates the account for a first-time single sign-on user
+// in users, the provider's organization.
+func provisionSSOUser(ctx context.Context, users *tenant.Collection, provider *oidc.Provider, identity *oidc.Identity, email, role string) (*models.User, int, error) {
+	if !provider.AutoProvision {
+		return nil, http.StatusForbidden, errors.New("No account exists for this identity")
+	}
+	if role == "" {
+		role = provider.DefaultRole
+	}
+	if role == "" {
+		role = auth.RoleMember
+	}
+	if !auth.ValidRole(role) {
+		return nil, http.StatusInternalServerError, errors.New("OIDC default role is invalid")
+	}
+	name := strings.TrimSpace(identity.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+	if len(name) > maxNameLength {
+		name = name[:maxNameLength]
+	}
+	now := time.Now()
+	user := models.User{
+		ID:              primitive.NewObjectID(),
+		Name:            name,
+		Email:           email,
+		Role:            role,
+		OIDCSubject:     identity.Subject,
+		EmailVerified:   true,
+		EmailVerifiedAt: &now,
+	}
+	if _, err := users.InsertOne(ctx, &user); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return &user, http.StatusOK, nil
+}
+
+func ssoDisabled(c *fiber.Ctx) error {
+	return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Single sign-on is not configured"})
+}
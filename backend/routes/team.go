@@ -3,46 +3,33 @@ package routes
 import (
 	"context"
 	"net/http"
-	"os"
 	"time"
 
+	"backend/auth"
 	"backend/models"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func RegisterTeamRoutes(app *fiber.App, db *mongo.Database) {
-	jwtSecret := []byte(os.Getenv("JWT_SECRET"))
-	authRequired := func(c *fiber.Ctx) error {
-		tokenStr := c.Get("Authorization")
-		if tokenStr == "" || len(tokenStr) < 8 {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Missing or invalid token"})
-		}
-		tokenStr = tokenStr[7:]
-		token, err := jwt.ParseWithClaims(tokenStr, jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
-		if err != nil || !token.Valid {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
-		}
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
-		}
-		c.Locals("userId", claims["id"])
-		c.Locals("userRole", claims["role"])
-		return c.Next()
-	}
+func RegisterTeamRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager) {
+	authRequired := authn.Required()
 
-	teamCol := db.Collection("teams")
+	// leadOfTeam allows the lead of the team named by :id.
+	leadOfTeam := func(c *fiber.Ctx, p *auth.Principal) (bool, error) {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return false, nil
+		}
+		count, err := orgDB(c, db).Collection("teams").CountDocuments(context.Background(), bson.M{"_id": id, "lead": p.UserID})
+		return count > 0, err
+	}
 
 	app.Get("/api/teams", authRequired, func(c *fiber.Ctx) error {
 		ctx := context.Background()
-		cur, err := teamCol.Find(ctx, bson.M{})
+		cur, err := orgDB(c, db).Collection("teams").Find(ctx, bson.M{})
 		if err != nil {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
@@ -59,14 +46,14 @@ func RegisterTeamRoutes(app *fiber.App, db *mongo.Database) {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid team id"})
 		}
 		var team models.Team
-		err = teamCol.FindOne(context.Background(), bson.M{"_id": id}).Decode(&team)
+		err = orgDB(c, db).Collection("teams").FindOne(context.Background(), bson.M{"_id": id}).Decode(&team)
 		if err != nil {
 			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Team not found"})
 		}
 		return c.JSON(team)
 	})
 
-	app.Post("/api/teams", authRequired, func(c *fiber.Ctx) error {
+	app.Post("/api/teams", authRequired, auth.Authorize(auth.Has(auth.PermTeamsCreate)), func(c *fiber.Ctx) error {
 		var req struct {
 			Name        string   `json:"name"`
 			Description string   `json:"description"`
@@ -92,14 +79,14 @@ func RegisterTeamRoutes(app *fiber.App, db *mongo.Database) {
 			Lead:        leadObjID,
 			CreatedAt:   time.Now(),
 		}
-		_, err := teamCol.InsertOne(context.Background(), team)
+		_, err := orgDB(c, db).Collection("teams").InsertOne(context.Background(), &team)
 		if err != nil {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.JSON(team)
 	})
 
-	app.Put("/api/teams/:id", authRequired, func(c *fiber.Ctx) error {
+	app.Put("/api/teams/:id", authRequired, auth.Authorize(auth.Has(auth.PermTeamsUpdate), leadOfTeam), func(c *fiber.Ctx) error {
 		id, err := primitive.ObjectIDFromHex(c.Params("id"))
 		if err != nil {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid team id"})
@@ -121,28 +108,55 @@ func RegisterTeamRoutes(app *fiber.App, db *mongo.Database) {
 			}
 		}
 		leadObjID, _ := primitive.ObjectIDFromHex(req.Lead)
+		// Lead hanya boleh mengubah nama dan deskripsi; anggota dan lead
+		// menentukan data siapa yang bisa dia lihat dan setujui
+		if !auth.PrincipalFrom(c).Can(auth.PermTeamsUpdate) {
+			var team models.Team
+			if err := orgDB(c, db).Collection("teams").FindOne(context.Background(), bson.M{"_id": id}).Decode(&team); err != nil {
+				return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Team not found"})
+			}
+			if leadObjID != team.Lead || !sameIDs(memberObjIDs, team.Members) {
+				return auth.Forbidden(c)
+			}
+		}
 		update := bson.M{
 			"name":        req.Name,
 			"description": req.Description,
 			"members":     memberObjIDs,
 			"lead":        leadObjID,
 		}
-		_, err = teamCol.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": update})
+		_, err = orgDB(c, db).Collection("teams").UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": update})
 		if err != nil {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.JSON(fiber.Map{"success": true})
 	})
 
-	app.Delete("/api/teams/:id", authRequired, func(c *fiber.Ctx) error {
+	app.Delete("/api/teams/:id", authRequired, auth.Authorize(auth.Has(auth.PermTeamsDelete)), func(c *fiber.Ctx) error {
 		id, err := primitive.ObjectIDFromHex(c.Params("id"))
 		if err != nil {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid team id"})
 		}
-		_, err = teamCol.DeleteOne(context.Background(), bson.M{"_id": id})
+		_, err = orgDB(c, db).Collection("teams").DeleteOne(context.Background(), bson.M{"_id": id})
 		if err != nil {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.JSON(fiber.Map{"success": true})
 	})
 }
+
+// sameIDs reports whether a and b hold the same ids, in any order.
+func sameIDs(a, b []primitive.ObjectID) bool {
+	set := map[primitive.ObjectID]bool{}
+	for _, id := range a {
+		set[id] = true
+	}
+	other := map[primitive.ObjectID]bool{}
+	for _, id := range b {
+		if !set[id] {
+			return false
+		}
+		other[id] = true
+	}
+	return len(set) == len(other)
+}
@@ -0,0 +1,105 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var errUnknownTimezone = errors.New("Unknown timezone")
+
+// loadTimezone resolves an IANA name such as "Asia/Jakarta", or a fixed
+// offset as offered by the settings page: "utc", "utc+7", "utc-5:30".
+func loadTimezone(name string) (*time.Location, error) {
+	lower := strings.ToLower(strings.TrimSpace(name))
+	if rest, ok := strings.CutPrefix(lower, "utc"); ok && !strings.Contains(rest, "/") {
+		if rest == "" {
+			return time.UTC, nil
+		}
+		sign := 1
+		switch rest[0] {
+		case '+':
+		case '-':
+			sign = -1
+		default:
+			return nil, errUnknownTimezone
+		}
+		hh, mm, _ := strings.Cut(rest[1:], ":")
+		h, errH := strconv.Atoi(hh)
+		m := 0
+		var errM error
+		if mm != "" {
+			m, errM = strconv.Atoi(mm)
+		}
+		if errH != nil || errM != nil || h > 14 || m >= 60 || (m != 0 && m != 30 && m != 45) {
+			return nil, errUnknownTimezone
+		}
+		offset := sign * (h*3600 + m*60)
+		return time.FixedZone(fmt.Sprintf("UTC%s", rest), offset), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" || name == "Local" {
+		return nil, errUnknownTimezone
+	}
+	return loc, nil
+}
+
+// userLocation returns the timezone that defines a user's days: their own
+// setting, else their organization's, else UTC.
+func userLocation(ctx context.Context, db *mongo.Database, userID, orgID primitive.ObjectID) (*time.Location, error) {
+	var user models.User
+	err := db.Collection("users").FindOne(ctx, bson.M{"_id": userID, "orgId": orgID},
+		options.FindOne().SetProjection(bson.M{"timezone": 1})).Decode(&user)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+	var org models.Organization
+	err = db.Collection("organizations").FindOne(ctx, bson.M{"_id": orgID},
+		options.FindOne().SetProjection(bson.M{"settings.timezone": 1})).Decode(&org)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+	return locationFor(user.Timezone, org.Settings.Timezone), nil
+}
+
+// locationFor picks the first valid timezone of a user's and their
+// organization's settings, falling back to UTC.
+func locationFor(userTZ, orgTZ string) *time.Location {
+	for _, tz := range []string{userTZ, orgTZ} {
+		if tz == "" {
+			continue
+		}
+		if loc, err := loadTimezone(tz); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// parseClock parses a local time of day "HH:MM" into its offset from
+// midnight.
+func parseClock(v string) (time.Duration, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, errors.New("Time must be HH:MM")
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// dayBounds returns the start of the calendar day containing t in loc and
+// the start of the next one. Days around DST changes are not 24 hours long.
+func dayBounds(t time.Time, loc *time.Location) (time.Time, time.Time) {
+	y, m, d := t.In(loc).Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 0, 1)
+}
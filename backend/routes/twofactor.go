@@ -0,0 +1,286 @@
+package routes
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"backend/auth"
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// totpIssuer is the account label shown in authenticator apps.
+func totpIssuer() string {
+	if v := os.Getenv("TOTP_ISSUER"); v != "" {
+		return v
+	}
+	return "Wellbeing Check"
+}
+
+// startLoginSession finishes a login once every required factor has been
+// checked. Users whose role requires two-factor authentication but who have
+// not enrolled get an enrollment-only session.
+func startLoginSession(c *fiber.Ctx, db *mongo.Database, authn *auth.Manager, user models.User) error {
+	if user.Deactivated {
+		return accountDeactivated(c)
+	}
+	ctx := context.Background()
+	enroll := false
+	if !user.TOTPEnabled {
+		required, err := mfaRequired(ctx, db, user)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		enroll = required
+	}
+	start := authn.StartSession
+	if enroll {
+		start = authn.StartEnrollmentSession
+	}
+	tokens, err := start(ctx, user, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
+	}
+	res := fiber.Map{"token": tokens.AccessToken, "refreshToken": tokens.RefreshToken, "user": fiber.Map{"id": user.ID.Hex(), "name": user.Name, "email": user.Email, "role": user.Role}}
+	if enroll {
+		res["mfaEnrollmentRequired"] = true
+	}
+	return c.JSON(res)
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery
+// code and burns it so it cannot be replayed.
+func checkSecondFactor(ctx context.Context, userCol *mongo.Collection, user models.User, code, recoveryCode string) (bool, error) {
+	if !user.TOTPEnabled {
+		return false, nil
+	}
+	if recoveryCode != "" {
+		hash, ok := auth.MatchRecoveryCode(user.RecoveryCodes, recoveryCode)
+		if !ok {
+			return false, nil
+		}
+		res, err := userCol.UpdateOne(ctx, bson.M{"_id": user.ID, "recoveryCodes": hash}, bson.M{"$pull": bson.M{"recoveryCodes": hash}})
+		if err != nil {
+			return false, err
+		}
+		return res.ModifiedCount > 0, nil
+	}
+	step, ok := auth.VerifyTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	res, err := userCol.UpdateOne(ctx,
+		bson.M{"_id": user.ID, "$or": bson.A{
+			bson.M{"totpLastStep": bson.M{"$lt": step}},
+			bson.M{"totpLastStep": bson.M{"$exists": false}},
+		}},
+		bson.M{"$set": bson.M{"totpLastStep": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+func RegisterTwoFactorRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager) {
+	authRequired := authn.Required()
+	enrollmentAllowed := authn.RequiredAllowEnrollment()
+	userCol := db.Collection("users")
+
+	// POST /api/auth/login/2fa - langkah kedua login dengan kode TOTP atau recovery code
+	app.Post("/api/auth/login/2fa", func(c *fiber.Ctx) error {
+		var req struct {
+			ChallengeToken string `json:"challengeToken"`
+			Code           string `json:"code"`
+			RecoveryCode   string `json:"recoveryCode"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		userID, err := authn.ParseChallenge(req.ChallengeToken)
+		if err != nil {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Login challenge is invalid or has expired"})
+		}
+		ctx := context.Background()
+		var user models.User
+		if err := userCol.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Login challenge is invalid or has expired"})
+		}
+		if err := authn.CheckLogin(ctx, user.Email, c.IP()); err != nil {
+			return loginThrottled(c, err)
+		}
+		ok, err := checkSecondFactor(ctx, userCol, user, req.Code, req.RecoveryCode)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !ok {
+			if err := authn.LoginFailed(ctx, user.Email, c.IP()); err != nil {
+				log.Printf("Recording failed login: %v", err)
+			}
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid authentication code"})
+		}
+		if err := authn.LoginSucceeded(ctx, user.Email); err != nil {
+			log.Printf("Clearing failed logins: %v", err)
+		}
+		return startLoginSession(c, db, authn, user)
+	})
+
+	app.Post("/api/user/2fa/setup", enrollmentAllowed, func(c *fiber.Ctx) error {
+		p := auth.PrincipalFrom(c)
+		ctx := context.Background()
+		var user models.User
+		if err := orgDB(c, db).Collection("users").FindOne(ctx, bson.M{"_id": p.UserID}).Decode(&user); err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		}
+		if user.TOTPEnabled {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+		}
+		secret, err := auth.NewTOTPSecret()
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if _, err := orgDB(c, db).Collection("users").UpdateOne(ctx, bson.M{"_id": p.UserID}, bson.M{"$set": bson.M{"totpPendingSecret": secret}}); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"secret": secret, "otpauthUri": auth.TOTPURI(totpIssuer(), user.Email, secret)})
+	})
+
+	// POST /api/user/2fa/enable - konfirmasi kode pertama; recovery code hanya ditampilkan sekali
+	app.Post("/api/user/2fa/enable", enrollmentAllowed, func(c *fiber.Ctx) error {
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		p := auth.PrincipalFrom(c)
+		ctx := context.Background()
+		users := orgDB(c, db).Collection("users")
+		var user models.User
+		if err := users.FindOne(ctx, bson.M{"_id": p.UserID}).Decode(&user); err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		}
+		if user.TOTPEnabled {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+		}
+		if user.TOTPPendingSecret == "" {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Start two-factor setup first"})
+		}
+		step, ok := auth.VerifyTOTP(user.TOTPPendingSecret, req.Code, time.Now())
+		if !ok {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid authentication code"})
+		}
+		codes, hashes, err := auth.NewRecoveryCodes()
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		_, err = users.UpdateOne(ctx,
+			bson.M{"_id": p.UserID, "totpPendingSecret": user.TOTPPendingSecret},
+			bson.M{
+				"$set": bson.M{
+					"totpEnabled":   true,
+					"totpSecret":    user.TOTPPendingSecret,
+					"totpLastStep":  step,
+					"recoveryCodes": hashes,
+				},
+				"$unset": bson.M{"totpPendingSecret": ""},
+			},
+		)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		res := fiber.Map{"success": true, "recoveryCodes": codes}
+		if p.EnrollOnly {
+			// Sesi khusus enrollment diganti dengan sesi penuh
+			if _, err := authn.RevokeSession(ctx, p.UserID, p.SessionID); err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			tokens, err := authn.StartSession(ctx, user, c.Get(fiber.HeaderUserAgent), c.IP())
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
+			}
+			res["token"] = tokens.AccessToken
+			res["refreshToken"] = tokens.RefreshToken
+		}
+		return c.JSON(res)
+	})
+
+	app.Post("/api/user/2fa/disable", authRequired, func(c *fiber.Ctx) error {
+		var req struct {
+			Password     string `json:"password"`
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recoveryCode"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		p := auth.PrincipalFrom(c)
+		ctx := context.Background()
+		var user models.User
+		if err := orgDB(c, db).Collection("users").FindOne(ctx, bson.M{"_id": p.UserID}).Decode(&user); err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		}
+		required, err := mfaRequired(ctx, db, user)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if required {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Two-factor authentication is required for your role", "code": "mfa_required"})
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Current password is incorrect"})
+		}
+		ok, err := checkSecondFactor(ctx, userCol, user, req.Code, req.RecoveryCode)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !ok {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid authentication code"})
+		}
+		_, err = orgDB(c, db).Collection("users").UpdateOne(ctx, bson.M{"_id": p.UserID}, bson.M{
+			"$set":   bson.M{"totpEnabled": false},
+			"$unset": bson.M{"totpSecret": "", "totpPendingSecret": "", "totpLastStep": "", "recoveryCodes": ""},
+		})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	})
+
+	app.Post("/api/user/2fa/recovery-codes", authRequired, func(c *fiber.Ctx) error {
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		p := auth.PrincipalFrom(c)
+		ctx := context.Background()
+		var user models.User
+		if err := orgDB(c, db).Collection("users").FindOne(ctx, bson.M{"_id": p.UserID}).Decode(&user); err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		}
+		ok, err := checkSecondFactor(ctx, userCol, user, req.Code, "")
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !ok {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid authentication code"})
+		}
+		codes, hashes, err := auth.NewRecoveryCodes()
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if _, err := orgDB(c, db).Collection("users").UpdateOne(ctx, bson.M{"_id": p.UserID}, bson.M{"$set": bson.M{"recoveryCodes": hashes}}); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"recoveryCodes": codes})
+	})
+}
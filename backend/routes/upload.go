@@ -0,0 +1,224 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"backend/auth"
+	"backend/models"
+	"backend/storage"
+	"backend/tenant"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// storeSelfie validates an image, writes it and its thumbnail to the blob
+// store and records the upload for userID, dated at for retention.
+func storeSelfie(ctx context.Context, store storage.BlobStore, scoped *tenant.DB, userID primitive.ObjectID, data []byte, at time.Time) (*models.Upload, error) {
+	img, contentType, err := inspectImage(data)
+	if err != nil {
+		return nil, err
+	}
+	thumb, err := thumbnail(img, thumbnailSize)
+	if err != nil {
+		return nil, err
+	}
+	id := primitive.NewObjectID()
+	prefix := "selfies/" + scoped.OrgID().Hex() + "/" + userID.Hex() + "/" + id.Hex()
+	up := models.Upload{
+		ID:           id,
+		UserID:       userID,
+		Key:          prefix + selfieExtensions[contentType],
+		ThumbnailKey: prefix + "_thumb.jpg",
+		ContentType:  contentType,
+		Size:         len(data),
+		Width:        img.Bounds().Dx(),
+		Height:       img.Bounds().Dy(),
+		CreatedAt:    at,
+	}
+	if err := store.Put(ctx, up.Key, contentType, data); err != nil {
+		return nil, err
+	}
+	if err := store.Put(ctx, up.ThumbnailKey, "image/jpeg", thumb); err != nil {
+		_ = store.Delete(ctx, up.Key)
+		return nil, err
+	}
+	if _, err := scoped.Collection("uploads").InsertOne(ctx, &up); err != nil {
+		_ = store.Delete(ctx, up.Key)
+		_ = store.Delete(ctx, up.ThumbnailKey)
+		return nil, err
+	}
+	return &up, nil
+}
+
+// unusedSelfie returns the caller's upload that no check-in uses yet.
+func unusedSelfie(ctx context.Context, scoped *tenant.DB, uploadID, userID primitive.ObjectID) (*models.Upload, error) {
+	var up models.Upload
+	err := scoped.Collection("uploads").FindOne(ctx, bson.M{"_id": uploadID, "userId": userID, "checkinId": bson.M{"$exists": false}}).Decode(&up)
+	return &up, err
+}
+
+// discardUpload removes an upload no check-in uses, with its blobs.
+func discardUpload(ctx context.Context, scoped *tenant.DB, store storage.BlobStore, up models.Upload) error {
+	if err := store.Delete(ctx, up.Key); err != nil {
+		return err
+	}
+	if err := store.Delete(ctx, up.ThumbnailKey); err != nil {
+		return err
+	}
+	_, err := scoped.Collection("uploads").DeleteOne(ctx, bson.M{"_id": up.ID})
+	return err
+}
+
+// signCheckins replaces stored selfie keys with signed URLs for the
+// response. Legacy check-ins keep their inline SelfieURL.
+func signCheckins(ctx context.Context, store storage.BlobStore, ttl time.Duration, checkins []models.Checkin) {
+	for i := range checkins {
+		ch := &checkins[i]
+		if ch.SelfieKey != "" {
+			ch.SelfieURL = signedURL(ctx, store, ttl, ch.SelfieKey)
+		}
+		if ch.SelfieThumbKey != "" {
+			ch.SelfieThumbnailURL = signedURL(ctx, store, ttl, ch.SelfieThumbKey)
+		}
+	}
+}
+
+// signCheckinDoc is signCheckins for check-ins read with a projection.
+func signCheckinDoc(ctx context.Context, store storage.BlobStore, ttl time.Duration, doc bson.M) {
+	if key, ok := doc["selfieKey"].(string); ok && key != "" {
+		doc["selfieUrl"] = signedURL(ctx, store, ttl, key)
+	}
+	if key, ok := doc["selfieThumbKey"].(string); ok && key != "" {
+		doc["selfieThumbnailUrl"] = signedURL(ctx, store, ttl, key)
+	}
+	delete(doc, "selfieKey")
+	delete(doc, "selfieThumbKey")
+}
+
+func signedURL(ctx context.Context, store storage.BlobStore, ttl time.Duration, key string) string {
+	u, err := store.SignedURL(ctx, key, ttl)
+	if err != nil {
+		log.Printf("Signing %s: %v", key, err)
+		return ""
+	}
+	return u
+}
+
+func uploadResponse(ctx context.Context, store storage.BlobStore, ttl time.Duration, up *models.Upload) fiber.Map {
+	url := signedURL(ctx, store, ttl, up.Key)
+	return fiber.Map{
+		"id":           up.ID,
+		"url":          url,
+		"imageUrl":     url,
+		"thumbnailUrl": signedURL(ctx, store, ttl, up.ThumbnailKey),
+		"expiresAt":    time.Now().Add(ttl),
+		"contentType":  up.ContentType,
+		"width":        up.Width,
+		"height":       up.Height,
+	}
+}
+
+// SignedURLTTL is how long selfie URLs in responses stay valid: 15 minutes
+// unless SIGNED_URL_TTL sets another duration.
+func SignedURLTTL() (time.Duration, error) {
+	v := os.Getenv("SIGNED_URL_TTL")
+	if v == "" {
+		return 15 * time.Minute, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, errors.New("SIGNED_URL_TTL must be a positive duration")
+	}
+	return d, nil
+}
+
+func RegisterUploadRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager, store storage.BlobStore, urlTTL time.Duration) {
+	authRequired := authn.Required()
+
+	// POST /api/upload - multipart "file" atau JSON {"image": "data:image/jpeg;base64,..."}
+	app.Post("/api/upload", authRequired, func(c *fiber.Ctx) error {
+		var data []byte
+		if fh, err := c.FormFile("file"); err == nil {
+			if fh.Size > maxSelfieSize {
+				return c.Status(http.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": errInvalidImage.Error()})
+			}
+			f, err := fh.Open()
+			if err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			}
+			defer f.Close()
+			if data, err = io.ReadAll(io.LimitReader(f, maxSelfieSize+1)); err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			}
+		} else {
+			var req struct {
+				Image string `json:"image"`
+			}
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			}
+			if data, err = decodeDataURL(req.Image); err != nil {
+				return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+			}
+		}
+		ctx := context.Background()
+		up, err := storeSelfie(ctx, store, orgDB(c, db), auth.PrincipalFrom(c).UserID, data, time.Now())
+		if errors.Is(err, errInvalidImage) {
+			return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			log.Printf("Storing upload: %v", err)
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store image"})
+		}
+		return c.Status(http.StatusCreated).JSON(uploadResponse(ctx, store, urlTTL, up))
+	})
+
+	// GET /api/uploads/:id - URL bertanda tangan yang baru untuk upload
+	app.Get("/api/uploads/:id", authRequired, func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid upload id"})
+		}
+		p := auth.PrincipalFrom(c)
+		filter := bson.M{"_id": id}
+		if !p.Can(auth.PermCheckinsReadAll) {
+			filter["userId"] = p.UserID
+		}
+		ctx := context.Background()
+		var up models.Upload
+		if err := orgDB(c, db).Collection("uploads").FindOne(ctx, filter).Decode(&up); err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Upload not found"})
+		}
+		return c.JSON(uploadResponse(ctx, store, urlTTL, &up))
+	})
+
+	// URL bertanda tangan LocalStore dilayani di sini; S3 melayaninya sendiri
+	if local, ok := store.(*storage.LocalStore); ok {
+		app.Get(storage.LocalFilesPath+"/*", func(c *fiber.Ctx) error {
+			key := c.Params("*")
+			if !local.Verify(key, c.Query("expires"), c.Query("sig")) {
+				return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Link is invalid or has expired"})
+			}
+			body, contentType, err := local.Get(context.Background(), key)
+			if errors.Is(err, storage.ErrNotFound) {
+				return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "File not found"})
+			}
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			c.Set(fiber.HeaderContentType, contentType)
+			c.Set(fiber.HeaderCacheControl, "private, max-age=300")
+			c.Set("X-Content-Type-Options", "nosniff")
+			return c.SendStream(body)
+		})
+	}
+}
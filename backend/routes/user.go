@@ -1,32 +1,63 @@
 package routes
 
 import (
+	"backend/auth"
+	"backend/mailer"
 	"backend/models"
+	"backend/tenant"
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"net/mail"
+	"net/url"
 	"os"
-	"time"
+	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func RegisterUserRoutes(app *fiber.App, db *mongo.Database) {
-	jwtSecret := []byte(os.Getenv("JWT_SECRET")) // Pindahkan ke dalam fungsi agar selalu update
+const (
+	maxNameLength     = 100
+	minPasswordLength = 8
+)
+
+func RegisterUserRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager, mail mailer.Mailer) {
+	authRequired := authn.Required()
+	avatarDir := os.Getenv("AVATAR_DIR")
+	if avatarDir == "" {
+		avatarDir = defaultAvatarDir
+	}
+
 	app.Post("/api/auth/register", func(c *fiber.Ctx) error {
+		if inviteOnly() {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Registration is by invitation only", "code": "invite_only"})
+		}
 		var req struct {
-			Name     string `json:"name"`
-			Email    string `json:"email"`
-			Password string `json:"password"`
+			Name         string `json:"name"`
+			Email        string `json:"email"`
+			Password     string `json:"password"`
+			Organization string `json:"organization"` // slug, kosong = organisasi default
 		}
 		if err := c.BodyParser(&req); err != nil {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
+		org, err := findOrg(context.Background(), db, req.Organization)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Unknown organization"})
+		}
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if org.Settings.InviteOnly {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Registration is by invitation only", "code": "invite_only"})
+		}
 		// Debug log
 		log.Printf("Register body: %+v", req)
 		if req.Name == "" || req.Email == "" || req.Password == "" {
@@ -50,12 +81,15 @@ func RegisterUserRoutes(app *fiber.App, db *mongo.Database) {
 			Password: string(hash),
 			Role:     "member",
 		}
-		_, err = db.Collection("users").InsertOne(context.Background(), user)
+		_, err = tenant.New(db, org.ID).Collection("users").InsertOne(context.Background(), &user)
 		if err != nil {
 			log.Printf("Insert error: %v", err)
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
-		return c.Status(http.StatusCreated).JSON(fiber.Map{"id": user.ID.Hex(), "name": user.Name, "email": user.Email, "role": user.Role})
+		if err := sendEmailVerification(context.Background(), authn, mail, user); err != nil {
+			log.Printf("Verification email for %s: %v", user.ID.Hex(), err)
+		}
+		return c.Status(http.StatusCreated).JSON(fiber.Map{"id": user.ID.Hex(), "name": user.Name, "email": user.Email, "role": user.Role, "emailVerified": user.EmailVerified})
 	})
 
 	app.Post("/api/auth/login", func(c *fiber.Ctx) error {
@@ -66,68 +100,212 @@ func RegisterUserRoutes(app *fiber.App, db *mongo.Database) {
 		if err := c.BodyParser(&req); err != nil {
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
+		ctx := context.Background()
+		if err := authn.CheckLogin(ctx, req.Email, c.IP()); err != nil {
+			return loginThrottled(c, err)
+		}
 		var user models.User
-		err := db.Collection("users").FindOne(context.Background(), bson.M{"email": req.Email}).Decode(&user)
+		err := db.Collection("users").FindOne(ctx, bson.M{"email": req.Email}).Decode(&user)
+		if err == nil {
+			err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
+		}
 		if err != nil {
+			if err := authn.LoginFailed(ctx, req.Email, c.IP()); err != nil {
+				log.Printf("Recording failed login: %v", err)
+			}
 			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid email or password"})
 		}
-		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid email or password"})
+		if user.Deactivated {
+			return accountDeactivated(c)
 		}
-		// Generate JWT
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-			"id":    user.ID.Hex(),
-			"name":  user.Name,
-			"email": user.Email,
-			"role":  user.Role,
-			"exp":   time.Now().Add(24 * time.Hour).Unix(),
-		})
-		tokenString, err := token.SignedString(jwtSecret)
+		if user.PasswordResetRequired {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "A password reset is required. Check your email for the reset link", "code": "password_reset_required"})
+		}
+		if user.TOTPEnabled {
+			// Password benar, tapi token baru diberikan setelah kode 2FA di /api/auth/login/2fa
+			challenge, err := authn.IssueChallenge(user)
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
+			}
+			return c.JSON(fiber.Map{"mfaRequired": true, "challengeToken": challenge})
+		}
+		if err := authn.LoginSucceeded(ctx, user.Email); err != nil {
+			log.Printf("Clearing failed logins: %v", err)
+		}
+		// Generate JWT + refresh token
+		return startLoginSession(c, db, authn, user)
+	})
+
+	app.Get("/api/user/profile", authn.RequiredAllowEnrollment(), func(c *fiber.Ctx) error {
+		p := auth.PrincipalFrom(c)
+		var user models.User
+		err := orgDB(c, db).Collection("users").FindOne(context.Background(), bson.M{"_id": p.UserID}).Decode(&user)
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate token"})
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
 		}
-		return c.JSON(fiber.Map{"token": tokenString, "user": fiber.Map{"id": user.ID.Hex(), "name": user.Name, "email": user.Email, "role": user.Role}})
+		return c.JSON(userProfile(user))
 	})
 
-	// Middleware JWT
-	authRequired := func(c *fiber.Ctx) error {
-		tokenStr := c.Get("Authorization")
-		if tokenStr == "" || len(tokenStr) < 8 {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Missing or invalid token"})
-		}
-		tokenStr = tokenStr[7:] // Bearer ...
-		token, err := jwt.ParseWithClaims(tokenStr, jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
-		if err != nil || !token.Valid {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
-		}
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
-		}
-		c.Locals("userId", claims["id"])
-		c.Locals("userRole", claims["role"])
-		return c.Next()
-	}
+	app.Put("/api/user/profile", authRequired, func(c *fiber.Ctx) error {
+		var req struct {
+			Name     *string `json:"name"`
+			Email    *string `json:"email"`
+			Avatar   *string `json:"avatar"`
+			Timezone *string `json:"timezone"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		p := auth.PrincipalFrom(c)
+		ctx := context.Background()
+		update := bson.M{}
+		if req.Name != nil {
+			name := strings.TrimSpace(*req.Name)
+			if name == "" || len(name) > maxNameLength {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Name must be between 1 and 100 characters"})
+			}
+			update["name"] = name
+		}
+		if req.Email != nil {
+			// Dengan email baru, lupa password bisa mengambil alih akun
+			if !p.APIKeyID.IsZero() {
+				return auth.Forbidden(c)
+			}
+			email := strings.TrimSpace(*req.Email)
+			if !validEmail(email) {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid email address"})
+			}
+			count, err := db.Collection("users").CountDocuments(ctx, bson.M{"email": email, "_id": bson.M{"$ne": p.UserID}})
+			if err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			if count > 0 {
+				return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Email already registered"})
+			}
+			update["email"] = email
+			// Alamat baru harus diverifikasi ulang
+			update["emailVerified"] = false
+		}
+		if req.Avatar != nil {
+			avatar := strings.TrimSpace(*req.Avatar)
+			if avatar != "" && !validAvatarURL(avatar) {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Avatar must be an http(s) URL or an uploaded avatar"})
+			}
+			update["avatar"] = avatar
+		}
+		if req.Timezone != nil {
+			tz := strings.TrimSpace(*req.Timezone)
+			if tz != "" {
+				if _, err := loadTimezone(tz); err != nil {
+					return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Unknown timezone"})
+				}
+			}
+			update["timezone"] = tz
+		}
+		filter := bson.M{"_id": p.UserID}
+		if email, ok := update["email"]; ok {
+			// Only reset verification when the address actually changes.
+			var current models.User
+			if err := orgDB(c, db).Collection("users").FindOne(ctx, filter).Decode(&current); err != nil {
+				return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+			}
+			if current.Email == email {
+				delete(update, "email")
+				delete(update, "emailVerified")
+			}
+		}
+		if len(update) == 0 {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "No fields to update"})
+		}
+		var user models.User
+		err := orgDB(c, db).Collection("users").FindOneAndUpdate(ctx, filter, bson.M{"$set": update},
+			options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&user)
+		if err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		}
+		if _, ok := update["email"]; ok {
+			if err := sendEmailVerification(ctx, authn, mail, user); err != nil {
+				log.Printf("Verification email for %s: %v", user.ID.Hex(), err)
+			}
+		}
+		return c.JSON(userProfile(user))
+	})
 
-	app.Get("/api/user/profile", authRequired, func(c *fiber.Ctx) error {
-		userId, ok := c.Locals("userId").(string)
-		if !ok {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	// POST /api/user/password - ganti password, lalu keluarkan sesi lain
+	app.Post("/api/user/password", authRequired, func(c *fiber.Ctx) error {
+		var req struct {
+			CurrentPassword string `json:"currentPassword"`
+			NewPassword     string `json:"newPassword"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if len(req.NewPassword) < minPasswordLength {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "New password must be at least 8 characters"})
 		}
-		objId, _ := primitive.ObjectIDFromHex(userId)
+		p := auth.PrincipalFrom(c)
+		ctx := context.Background()
 		var user models.User
-		err := db.Collection("users").FindOne(context.Background(), bson.M{"_id": objId}).Decode(&user)
+		if err := orgDB(c, db).Collection("users").FindOne(ctx, bson.M{"_id": p.UserID}).Decode(&user); err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Current password is incorrect"})
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to hash password"})
+		}
+		if _, err := orgDB(c, db).Collection("users").UpdateOne(ctx, bson.M{"_id": p.UserID}, bson.M{"$set": bson.M{"password": string(hash)}}); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		revoked, err := authn.RevokeAllSessions(ctx, p.UserID, p.SessionID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"success": true, "revokedSessions": revoked})
+	})
+
+	app.Static(avatarURLPrefix, avatarDir)
+
+	// POST /api/user/avatar - upload foto profil (multipart, field "avatar")
+	app.Post("/api/user/avatar", authRequired, func(c *fiber.Ctx) error {
+		file, err := c.FormFile("avatar")
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Avatar file is required"})
+		}
+		p := auth.PrincipalFrom(c)
+		avatarURL, err := saveAvatar(avatarDir, file, p.UserID)
+		if errors.Is(err, errInvalidAvatar) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Avatar must be a JPEG, PNG, GIF or WebP image up to 2 MB"})
+		}
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		var old models.User
+		err = orgDB(c, db).Collection("users").FindOneAndUpdate(context.Background(), bson.M{"_id": p.UserID}, bson.M{"$set": bson.M{"avatar": avatarURL}}).Decode(&old)
 		if err != nil {
+			removeAvatar(avatarDir, avatarURL)
 			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
 		}
-		return c.JSON(fiber.Map{"id": user.ID.Hex(), "name": user.Name, "email": user.Email, "role": user.Role})
+		removeAvatar(avatarDir, old.Avatar)
+		return c.JSON(fiber.Map{"avatar": avatarURL})
 	})
 
-	app.Get("/api/users", func(c *fiber.Ctx) error {
+	app.Delete("/api/user/avatar", authRequired, func(c *fiber.Ctx) error {
+		p := auth.PrincipalFrom(c)
+		var old models.User
+		err := orgDB(c, db).Collection("users").FindOneAndUpdate(context.Background(), bson.M{"_id": p.UserID}, bson.M{"$unset": bson.M{"avatar": ""}}).Decode(&old)
+		if err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		}
+		removeAvatar(avatarDir, old.Avatar)
+		return c.JSON(fiber.Map{"success": true})
+	})
+
+	app.Get("/api/users", authRequired, auth.Authorize(auth.Has(auth.PermUsersList)), func(c *fiber.Ctx) error {
 		ctx := context.Background()
-		cur, err := db.Collection("users").Find(ctx, bson.M{})
+		cur, err := orgDB(c, db).Collection("users").Find(ctx, bson.M{})
 		if err != nil {
 			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
@@ -149,3 +327,34 @@ func RegisterUserRoutes(app *fiber.App, db *mongo.Database) {
 		return c.JSON(result)
 	})
 }
+
+// userProfile is the public view of a user returned by profile endpoints.
+func userProfile(u models.User) fiber.Map {
+	return fiber.Map{"id": u.ID.Hex(), "name": u.Name, "email": u.Email, "role": u.Role, "avatar": u.Avatar, "emailVerified": u.EmailVerified, "timezone": u.Timezone}
+}
+
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+func validAvatarURL(avatar string) bool {
+	if strings.HasPrefix(avatar, avatarURLPrefix+"/") {
+		return true
+	}
+	u, err := url.Parse(avatar)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// loginThrottled answers a login attempt refused by the lockout policy.
+func loginThrottled(c *fiber.Ctx, err error) error {
+	var throttled *auth.ErrLoginThrottled
+	if !errors.As(err, &throttled) {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(throttled.RetryAfter.Seconds())+1))
+	if throttled.Locked {
+		return c.Status(http.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many failed attempts. Try again later.", "code": "account_locked"})
+	}
+	return c.Status(http.StatusTooManyRequests).JSON(fiber.Map{"error": "Too many failed attempts. Please wait before retrying.", "code": "login_throttled"})
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// fieldError describes one invalid field of a request body.
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validationFailed writes the 422 response listing every invalid field.
+func validationFailed(c *fiber.Ctx, errs []fieldError) error {
+	return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+		"error":  "Validation failed",
+		"code":   "validation_failed",
+		"fields": errs,
+	})
+}
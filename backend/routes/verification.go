@@ -0,0 +1,143 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"backend/auth"
+	"backend/mailer"
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	emailVerificationTTL = 48 * time.Hour
+	verificationCooldown = time.Minute
+)
+
+// BackfillEmailVerified marks accounts created before email verification
+// existed as verified so they keep access to check-ins.
+func BackfillEmailVerified(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("users").UpdateMany(ctx,
+		bson.M{"emailVerified": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"emailVerified": true}},
+	)
+	return err
+}
+
+// verifiedEmailRequired blocks callers whose email address is still
+// unverified.
+func verifiedEmailRequired(db *mongo.Database) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		p := auth.PrincipalFrom(c)
+		count, err := db.Collection("users").CountDocuments(context.Background(), bson.M{"_id": p.UserID, "emailVerified": false})
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if count > 0 {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Please verify your email address first", "code": "email_unverified"})
+		}
+		return c.Next()
+	}
+}
+
+func RegisterVerificationRoutes(app *fiber.App, db *mongo.Database, authn *auth.Manager, mail mailer.Mailer) {
+	authRequired := authn.Required()
+	userCol := db.Collection("users")
+
+	app.Post("/api/auth/verify-email", func(c *fiber.Ctx) error {
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		ctx := context.Background()
+		userID, err := authn.ConsumeUserToken(ctx, req.Token, auth.PurposeVerifyEmail)
+		if errors.Is(err, auth.ErrInvalidUserToken) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Verification link is invalid or has expired"})
+		}
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err := markEmailVerified(ctx, userCol, userID); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	})
+
+	// POST /api/auth/verify-email/resend - kirim ulang link verifikasi (maks. sekali per menit)
+	app.Post("/api/auth/verify-email/resend", authRequired, func(c *fiber.Ctx) error {
+		p := auth.PrincipalFrom(c)
+		ctx := context.Background()
+		var user models.User
+		if err := userCol.FindOne(ctx, bson.M{"_id": p.UserID}).Decode(&user); err != nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		}
+		if user.EmailVerified {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Email address is already verified"})
+		}
+		last, err := authn.LastUserTokenAt(ctx, user.ID, auth.PurposeVerifyEmail)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if wait := time.Until(last.Add(verificationCooldown)); wait > 0 {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(wait.Seconds())+1))
+			return c.Status(http.StatusTooManyRequests).JSON(fiber.Map{"error": "Please wait before requesting another email"})
+		}
+		if err := sendEmailVerification(ctx, authn, mail, user); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to send verification email"})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	})
+
+	app.Post("/api/users/:id/verify", authRequired, auth.Authorize(auth.Has(auth.PermUsersVerify)), func(c *fiber.Ctx) error {
+		id, err := primitive.ObjectIDFromHex(c.Params("id"))
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user id"})
+		}
+		res, err := orgDB(c, db).Collection("users").UpdateOne(context.Background(),
+			bson.M{"_id": id},
+			bson.M{"$set": bson.M{"emailVerified": true, "emailVerifiedAt": time.Now()}},
+		)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if res.MatchedCount == 0 {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	})
+}
+
+func markEmailVerified(ctx context.Context, userCol *mongo.Collection, userID primitive.ObjectID) error {
+	_, err := userCol.UpdateOne(ctx,
+		bson.M{"_id": userID, "emailVerified": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"emailVerified": true, "emailVerifiedAt": time.Now()}},
+	)
+	return err
+}
+
+func sendEmailVerification(ctx context.Context, authn *auth.Manager, mail mailer.Mailer, user models.User) error {
+	token, err := authn.IssueUserToken(ctx, user.ID, auth.PurposeVerifyEmail, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	link := appURL() + "/verify-email?token=" + url.QueryEscape(token)
+	return mail.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Wellbeing Check email address",
+		Body: "Hi " + user.Name + ",\n\n" +
+			"Please confirm your email address by opening the link below within 48 hours:\n\n" +
+			link + "\n\n" +
+			"You can request a new link from the app if this one expires.\n",
+	})
+}
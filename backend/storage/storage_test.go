@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"selfies/org/user/a.jpg", true},
+		{"a.jpg", true},
+		{"", false},
+		{"/etc/passwd", false},
+		{"selfies/../../etc/passwd", false},
+		{"selfies/./a.jpg", false},
+		{"selfies//a.jpg", false},
+		{"selfies/", false},
+		{`selfies\a.jpg`, false},
+		{"..", false},
+	}
+	for _, tt := range tests {
+		if got := validKey(tt.key); got != tt.want {
+			t.Errorf("validKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestFromEnvSigningKey(t *testing.T) {
+	t.Setenv("STORAGE_DRIVER", "local")
+	t.Setenv("STORAGE_SIGNING_KEY", "")
+
+	store, err := FromEnv("jwt-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	derived := store.(*LocalStore).SigningKey
+	if len(derived) == 0 || bytes.Equal(derived, []byte("jwt-secret")) {
+		t.Errorf("signing key %x is not derived from the secret", derived)
+	}
+
+	t.Setenv("STORAGE_SIGNING_KEY", "storage-key")
+	store, err = FromEnv("jwt-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(store.(*LocalStore).SigningKey); got != "storage-key" {
+		t.Errorf("signing key = %q, want STORAGE_SIGNING_KEY", got)
+	}
+
+	t.Setenv("STORAGE_SIGNING_KEY", "")
+	if _, err := FromEnv(""); err == nil {
+		t.Error("FromEnv without any key succeeded")
+	}
+}
+
+func TestLocalStoreSignedURL(t *testing.T) {
+	s := &LocalStore{Dir: t.TempDir(), BaseURL: "http://localhost/files", SigningKey: []byte("key")}
+	raw, err := s.SignedURL(context.Background(), "selfies/a.jpg", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := strings.TrimPrefix(u.Path, "/files/")
+	expires, sig := u.Query().Get("expires"), u.Query().Get("sig")
+	if !s.Verify(key, expires, sig) {
+		t.Fatalf("Verify rejected its own URL %s", raw)
+	}
+	if s.Verify("selfies/b.jpg", expires, sig) {
+		t.Error("Verify accepted the signature for another key")
+	}
+	if s.Verify(key, "1", sig) {
+		t.Error("Verify accepted an expired URL")
+	}
+	other := &LocalStore{SigningKey: []byte("other")}
+	if other.Verify(key, expires, sig) {
+		t.Error("Verify accepted a signature made with another key")
+	}
+	if _, err := s.SignedURL(context.Background(), "../a.jpg", time.Minute); err == nil {
+		t.Error("SignedURL accepted a key escaping the store")
+	}
+}
@@ -0,0 +1,181 @@
+// Package tenant scopes MongoDB access to a single organization. Every filter
+// passed through a Collection is narrowed to the organization and every
+// inserted document is stamped with it, so handlers cannot read or write
+// another tenant's data by forgetting a condition.
+package tenant
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Field is the document field holding the owning organization.
+const Field = "orgId"
+
+// Collections lists the tenant-scoped collections.
+var Collections = []string{"users", "teams", "projects", "checkins", "invitations", "attendance", "holidays", "leave_requests", "uploads", "selfie_purges", "mood_scales", "checkin_corrections", "checkin_revisions", "idempotency_keys"}
+
+// ErrOrgChange is returned for updates that try to move a document to
+// another organization.
+var ErrOrgChange = errors.New("tenant: documents cannot change organization")
+
+// ErrNoOrg is returned when a scope is used without an organization.
+var ErrNoOrg = errors.New("tenant: no organization in scope")
+
+// Document is implemented by models stored in scoped collections.
+type Document interface {
+	SetOrgID(id primitive.ObjectID)
+}
+
+// DB is a database view limited to one organization.
+type DB struct {
+	db    *mongo.Database
+	orgID primitive.ObjectID
+}
+
+func New(db *mongo.Database, orgID primitive.ObjectID) *DB {
+	return &DB{db: db, orgID: orgID}
+}
+
+func (d *DB) OrgID() primitive.ObjectID {
+	return d.orgID
+}
+
+func (d *DB) Collection(name string) *Collection {
+	return &Collection{col: d.db.Collection(name), orgID: d.orgID}
+}
+
+// Collection wraps a mongo.Collection, adding the organization to filters
+// and inserted documents.
+type Collection struct {
+	col   *mongo.Collection
+	orgID primitive.ObjectID
+}
+
+func (c *Collection) scope(filter bson.M) (bson.M, error) {
+	if c.orgID.IsZero() {
+		return nil, ErrNoOrg
+	}
+	scoped := bson.M{}
+	for k, v := range filter {
+		scoped[k] = v
+	}
+	scoped[Field] = c.orgID
+	return scoped, nil
+}
+
+func checkUpdate(update bson.M) error {
+	for op, fields := range update {
+		if op == "$set" || op == "$unset" || op == "$setOnInsert" || op == "$rename" {
+			if m, ok := fields.(bson.M); ok {
+				if _, ok := m[Field]; ok {
+					return ErrOrgChange
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Collection) Find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	f, err := c.scope(filter)
+	if err != nil {
+		return nil, err
+	}
+	return c.col.Find(ctx, f, opts...)
+}
+
+func (c *Collection) FindOne(ctx context.Context, filter bson.M, opts ...*options.FindOneOptions) *mongo.SingleResult {
+	f, err := c.scope(filter)
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+	return c.col.FindOne(ctx, f, opts...)
+}
+
+func (c *Collection) CountDocuments(ctx context.Context, filter bson.M, opts ...*options.CountOptions) (int64, error) {
+	f, err := c.scope(filter)
+	if err != nil {
+		return 0, err
+	}
+	return c.col.CountDocuments(ctx, f, opts...)
+}
+
+func (c *Collection) Distinct(ctx context.Context, field string, filter bson.M, opts ...*options.DistinctOptions) ([]any, error) {
+	f, err := c.scope(filter)
+	if err != nil {
+		return nil, err
+	}
+	return c.col.Distinct(ctx, field, f, opts...)
+}
+
+func (c *Collection) InsertOne(ctx context.Context, doc Document) (*mongo.InsertOneResult, error) {
+	if c.orgID.IsZero() {
+		return nil, ErrNoOrg
+	}
+	doc.SetOrgID(c.orgID)
+	return c.col.InsertOne(ctx, doc)
+}
+
+func (c *Collection) UpdateOne(ctx context.Context, filter, update bson.M, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	f, err := c.scope(filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUpdate(update); err != nil {
+		return nil, err
+	}
+	return c.col.UpdateOne(ctx, f, update, opts...)
+}
+
+func (c *Collection) UpdateMany(ctx context.Context, filter, update bson.M, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	f, err := c.scope(filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUpdate(update); err != nil {
+		return nil, err
+	}
+	return c.col.UpdateMany(ctx, f, update, opts...)
+}
+
+func (c *Collection) FindOneAndUpdate(ctx context.Context, filter, update bson.M, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	f, err := c.scope(filter)
+	if err == nil {
+		err = checkUpdate(update)
+	}
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+	return c.col.FindOneAndUpdate(ctx, f, update, opts...)
+}
+
+func (c *Collection) DeleteOne(ctx context.Context, filter bson.M, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	f, err := c.scope(filter)
+	if err != nil {
+		return nil, err
+	}
+	return c.col.DeleteOne(ctx, f, opts...)
+}
+
+func (c *Collection) DeleteMany(ctx context.Context, filter bson.M, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	f, err := c.scope(filter)
+	if err != nil {
+		return nil, err
+	}
+	return c.col.DeleteMany(ctx, f, opts...)
+}
+
+// Aggregate runs pipeline after an initial $match on the organization.
+func (c *Collection) Aggregate(ctx context.Context, pipeline []bson.M, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	if c.orgID.IsZero() {
+		return nil, ErrNoOrg
+	}
+	stages := append([]bson.M{{"$match": bson.M{Field: c.orgID}}}, pipeline...)
+	return c.col.Aggregate(ctx, stages, opts...)
+}